@@ -0,0 +1,199 @@
+/*
+ * Breakpoints and watchpoints
+ *
+ * Breakpoints stop Run and RunUntil when the PC reaches an address,
+ * memory in a range is read or written, an IOT is executed for a
+ * device or an interrupt is taken.  Watchpoints use the observer
+ * interface so there is no cost when none are set.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import "fmt"
+
+// BreakKind is what a Breakpoint is triggered by
+type BreakKind int
+
+const (
+	BreakPC        BreakKind = iota // The PC reaching an address
+	BreakRead                       // Memory being read
+	BreakWrite                      // Memory being written
+	BreakIOT                        // An IOT for a device
+	BreakInterrupt                  // An interrupt being taken
+)
+
+func (k BreakKind) String() string {
+	switch k {
+	case BreakPC:
+		return "PC"
+	case BreakRead:
+		return "read"
+	case BreakWrite:
+		return "write"
+	case BreakIOT:
+		return "IOT"
+	case BreakInterrupt:
+		return "interrupt"
+	}
+	return fmt.Sprintf("BreakKind(%d)", int(k))
+}
+
+// Breakpoint stops the machine when it is hit.  PC breakpoints stop
+// the machine before the instruction at the address is executed, the
+// others stop it once the instruction that hit them has finished.
+type Breakpoint struct {
+	Kind BreakKind
+	// The address for BreakPC, the range of addresses for BreakRead
+	// and BreakWrite or the device number for BreakIOT.  Bits 12-14
+	// of addresses are the field.
+	Start uint
+	End   uint
+	// If not nil the breakpoint is only hit if Cond returns true.  It
+	// is passed the registers at the time of the hit.
+	Cond   func(s State) bool
+	Ignore int // The number of hits to ignore before stopping
+	Hits   int // The number of times the breakpoint has been hit
+}
+
+// BreakOnPC returns a breakpoint for when the PC reaches pc.  Bits
+// 12-14 of pc are the instruction field.
+func BreakOnPC(pc uint) *Breakpoint {
+	pc &= 0o77777
+	return &Breakpoint{Kind: BreakPC, Start: pc, End: pc}
+}
+
+// WatchRead returns a watchpoint for when memory from start to end,
+// inclusive, is read as an operand or indirect address
+func WatchRead(start uint, end uint) *Breakpoint {
+	return &Breakpoint{Kind: BreakRead, Start: start & 0o77777, End: end & 0o77777}
+}
+
+// WatchWrite returns a watchpoint for when memory from start to end,
+// inclusive, is written
+func WatchWrite(start uint, end uint) *Breakpoint {
+	return &Breakpoint{Kind: BreakWrite, Start: start & 0o77777, End: end & 0o77777}
+}
+
+// BreakOnIOT returns a breakpoint for when an IOT is executed for device
+func BreakOnIOT(device uint) *Breakpoint {
+	device &= 0o77
+	return &Breakpoint{Kind: BreakIOT, Start: device, End: device}
+}
+
+// BreakOnInterrupt returns a breakpoint for when an interrupt is taken
+func BreakOnInterrupt() *Breakpoint {
+	return &Breakpoint{Kind: BreakInterrupt}
+}
+
+func (b *Breakpoint) String() string {
+	switch b.Kind {
+	case BreakPC:
+		return fmt.Sprintf("PC %05o", b.Start)
+	case BreakRead, BreakWrite:
+		return fmt.Sprintf("%s %05o-%05o", b.Kind, b.Start, b.End)
+	case BreakIOT:
+		return fmt.Sprintf("IOT %02o", b.Start)
+	}
+	return b.Kind.String()
+}
+
+// AddBreakpoint sets a breakpoint.  The same breakpoint can be
+// inspected later to find out how many times it has been hit.
+func (p *PDP8) AddBreakpoint(b *Breakpoint) {
+	if p.breaks == nil {
+		p.breaks = &breakpoints{p: p}
+		p.AddObserver(p.breaks)
+	}
+	p.breaks.list = append(p.breaks.list, b)
+}
+
+// RemoveBreakpoint removes a breakpoint set with AddBreakpoint
+func (p *PDP8) RemoveBreakpoint(b *Breakpoint) {
+	if p.breaks == nil {
+		return
+	}
+	list := p.breaks.list
+	for i, lb := range list {
+		if lb == b {
+			p.breaks.list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	// Remove the observer so that there is no cost without breakpoints
+	if len(p.breaks.list) == 0 {
+		p.RemoveObserver(p.breaks)
+		p.breaks = nil
+	}
+}
+
+// Breakpoints returns the breakpoints that have been set
+func (p *PDP8) Breakpoints() []*Breakpoint {
+	if p.breaks == nil {
+		return nil
+	}
+	return append([]*Breakpoint(nil), p.breaks.list...)
+}
+
+// breakpoints watches the machine and records the first
+// breakpoint hit during a cycle
+type breakpoints struct {
+	NopObserver
+	p    *PDP8
+	list []*Breakpoint
+	hit  *Breakpoint // The breakpoint to stop at, nil if none
+}
+
+func (bs *breakpoints) MemoryRead(addr uint, value uint) {
+	bs.match(BreakRead, addr)
+}
+
+func (bs *breakpoints) MemoryWrite(addr uint, oldValue uint, newValue uint) {
+	bs.match(BreakWrite, addr)
+}
+
+func (bs *breakpoints) IOT(pc uint, ir uint) {
+	bs.match(BreakIOT, (ir>>3)&0o77)
+}
+
+func (bs *breakpoints) InterruptTaken(pc uint) {
+	bs.match(BreakInterrupt, 0)
+}
+
+// match records a hit for each breakpoint of kind whose range
+// includes v
+func (bs *breakpoints) match(kind BreakKind, v uint) {
+	for _, b := range bs.list {
+		if b.Kind != kind {
+			continue
+		}
+		if kind != BreakInterrupt && (v < b.Start || v > b.End) {
+			continue
+		}
+		if b.Cond != nil && !b.Cond(bs.p.State()) {
+			continue
+		}
+		b.Hits++
+		if b.Hits > b.Ignore && bs.hit == nil {
+			bs.hit = b
+		}
+	}
+}
+
+// check is called at the end of each cycle and returns a *BreakError
+// if a breakpoint has been hit.  err is returned in preference.
+func (bs *breakpoints) check(err error) error {
+	pc := bs.p.ifr<<12 | bs.p.pc
+	if err == nil {
+		bs.match(BreakPC, pc)
+	}
+	b := bs.hit
+	bs.hit = nil
+	if err != nil || b == nil {
+		return err
+	}
+	return &BreakError{Breakpoint: b, PC: pc}
+}
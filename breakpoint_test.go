@@ -0,0 +1,143 @@
+package pdp8
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBreakOnPC(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o7000, // NOP
+		0o202: 0o5200, // JMP 200
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	b := BreakOnPC(0o201)
+	b.Ignore = 1
+	b.Cond = func(s State) bool { return s.AC >= 2 }
+	p.AddBreakpoint(b)
+
+	for _, wantAC := range []uint{3, 4} {
+		_, _, err = p.Run(500)
+		var be *BreakError
+		if !errors.As(err, &be) {
+			t.Fatalf("got error: %v, want: *BreakError", err)
+		}
+		if be.Breakpoint != b || be.PC != 0o201 || p.lac != wantAC {
+			t.Errorf("got breakpoint: %v, PC: %05o, AC: %04o, want: %v, PC: 00201, AC: %04o",
+				be.Breakpoint, be.PC, p.lac, b, wantAC)
+		}
+	}
+	if b.Hits != 3 {
+		t.Errorf("got hits: %d, want: 3", b.Hits)
+	}
+}
+
+func TestWatchWrite(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o3210, // DCA 210
+		0o202: 0o3220, // DCA 220
+		0o203: 0o7402, // HLT
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	b := WatchWrite(0o215, 0o225)
+	p.AddBreakpoint(b)
+	p.AddBreakpoint(WatchRead(0o210, 0o210))
+
+	stop, err := p.RunUntil(CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Breakpoint != b || stop.State.PC != 0o203 || stop.Cycles != 3 {
+		t.Errorf("got breakpoint: %v, PC: %04o, cycles: %d, want: %v, PC: 0203, cycles: 3",
+			stop.Breakpoint, stop.State.PC, stop.Cycles, b)
+	}
+}
+
+func TestBreakOnIOT(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6031, // KSF
+		0o201: 0o6001, // ION
+		0o202: 0o6046, // TLS
+		0o203: 0o5203, // JMP 203
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	iot := BreakOnIOT(0o4)
+	intr := BreakOnInterrupt()
+	p.AddBreakpoint(iot)
+	p.AddBreakpoint(intr)
+
+	// The interrupt is taken after the TLS, in the same cycle, but
+	// only the first breakpoint hit is reported
+	stop, err := p.RunUntil(CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Breakpoint != iot || intr.Hits != 1 {
+		t.Errorf("got breakpoint: %v, interrupt hits: %d, want: %v, interrupt hits: 1",
+			stop.Breakpoint, intr.Hits, iot)
+	}
+
+	p.RemoveBreakpoint(iot)
+	p.pc = 0o201
+	stop, err = p.RunUntil(CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Breakpoint != intr || stop.State.PC != 1 {
+		t.Errorf("got breakpoint: %v, PC: %04o, want: %v, PC: 0001",
+			stop.Breakpoint, stop.State.PC, intr)
+	}
+}
+
+func TestRemoveBreakpoint(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1 := BreakOnPC(0o200)
+	b2 := BreakOnInterrupt()
+	p.AddBreakpoint(b1)
+	p.AddBreakpoint(b2)
+
+	p.RemoveBreakpoint(b1)
+	if got := p.Breakpoints(); len(got) != 1 || got[0] != b2 {
+		t.Fatalf("breakpoints got: %v, want: [%v]", got, b2)
+	}
+	p.RemoveBreakpoint(b2)
+	if p.breaks != nil || p.observers != nil {
+		t.Errorf("got breaks: %v, observers: %v, want: nil, nil", p.breaks, p.observers)
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Reconstruction of the call stack
+ *
+ * The PDP-8 has no stack, a JMS stores the return address in the
+ * first word of the subroutine and the subroutine returns with a
+ * JMP I through that word.  The call stack is therefore rebuilt by
+ * following JMS instructions and matching JMP I instructions to the
+ * subroutine entries they jump through.  Interrupts are treated as a
+ * call to location 0 as they return with JMP I 0.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxCallDepth is the deepest call stack that will be followed,
+// this stops programs which JMS without returning using up memory
+const maxCallDepth = 64
+
+// noEntry is the entry address of the top level of a program
+const noEntry = ^uint(0)
+
+// subroutineName returns the name used for the subroutine at entry
+func subroutineName(entry uint) string {
+	switch entry {
+	case noEntry:
+		return "main"
+	case 0:
+		return "interrupt"
+	}
+	return fmt.Sprintf("sub_%05o", entry)
+}
+
+// returnEntry returns the address that a JMP I at pc jumps through,
+// which is the entry of the subroutine it returns from
+func returnEntry(pc uint, ir uint) (uint, bool) {
+	if (ir & 0o7400) != 0o5400 { // If not JMP I
+		return 0, false
+	}
+	ptr := ir & 0o177
+	if (ir & 0o200) == 0o200 {
+		ptr |= pc & 0o7600
+	}
+	return pc&0o70000 | ptr, true
+}
+
+// Frame is a subroutine on the call stack
+type Frame struct {
+	Entry     uint // The address of the subroutine, bits 12-14 are the field
+	CallPC    uint // The address of the JMS or the PC when interrupted
+	Interrupt bool // Whether called by an interrupt
+}
+
+func (f Frame) String() string {
+	if f.Interrupt {
+		return fmt.Sprintf("%s at %05o", subroutineName(f.Entry), f.CallPC)
+	}
+	return fmt.Sprintf("%s called from %05o", subroutineName(f.Entry), f.CallPC)
+}
+
+// CallStack is an Observer which keeps track of the subroutines
+// being executed and counts the calls between them.  It is attached
+// with AddObserver.
+type CallStack struct {
+	NopObserver
+	frames []Frame
+	calls  map[[2]uint]uint64 // Number of calls by caller and callee entry
+}
+
+// NewCallStack returns an empty CallStack
+func NewCallStack() *CallStack {
+	return &CallStack{calls: map[[2]uint]uint64{}}
+}
+
+func (cs *CallStack) InstructionExecuted(pc uint, ir uint, ea uint) {
+	if ir>>9 == 4 { // JMS
+		cs.call(Frame{Entry: ea, CallPC: pc})
+	} else if entry, ok := returnEntry(pc, ir); ok {
+		cs.ret(entry)
+	}
+}
+
+func (cs *CallStack) InterruptTaken(pc uint) {
+	cs.call(Frame{Entry: 0, CallPC: pc, Interrupt: true})
+}
+
+func (cs *CallStack) call(f Frame) {
+	cs.calls[[2]uint{cs.current(), f.Entry}]++
+	if len(cs.frames) >= maxCallDepth {
+		cs.frames = append(cs.frames[:0], cs.frames[1:]...)
+	}
+	cs.frames = append(cs.frames, f)
+}
+
+// ret returns from the subroutine at entry if it is on the call stack
+func (cs *CallStack) ret(entry uint) {
+	for i := len(cs.frames) - 1; i >= 0; i-- {
+		if cs.frames[i].Entry == entry {
+			cs.frames = cs.frames[:i]
+			return
+		}
+	}
+}
+
+// current returns the entry of the subroutine being executed
+func (cs *CallStack) current() uint {
+	if len(cs.frames) == 0 {
+		return noEntry
+	}
+	return cs.frames[len(cs.frames)-1].Entry
+}
+
+// Frames returns the call stack, innermost subroutine first
+func (cs *CallStack) Frames() []Frame {
+	r := make([]Frame, len(cs.frames))
+	for i, f := range cs.frames {
+		r[len(r)-1-i] = f
+	}
+	return r
+}
+
+// String returns the call stack, innermost subroutine first,
+// one per line
+func (cs *CallStack) String() string {
+	var sb strings.Builder
+	for _, f := range cs.Frames() {
+		sb.WriteString(f.String())
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(subroutineName(noEntry))
+	return sb.String()
+}
+
+// WriteDOT writes the call graph in Graphviz DOT format with the
+// edges labelled with the number of calls
+func (cs *CallStack) WriteDOT(w io.Writer) error {
+	edges := make([][2]uint, 0, len(cs.calls))
+	for e := range cs.calls {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		// Sort main first
+		a, b := edges[i][0]+1, edges[j][0]+1
+		return a < b || (a == b && edges[i][1] < edges[j][1])
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph calls {")
+	for _, e := range edges {
+		fmt.Fprintf(bw, "  %q -> %q [label=\"%d\"];\n",
+			subroutineName(e[0]), subroutineName(e[1]), cs.calls[e])
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
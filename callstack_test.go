@@ -0,0 +1,120 @@
+package pdp8
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCallStack(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o4210, // JMS 210
+		0o201: 0o4210, // JMS 210
+		0o202: 0o7402, // HLT
+		0o210: 0o0000, // Return address
+		0o211: 0o4220, // JMS 220
+		0o212: 0o5610, // JMP I 210
+		0o220: 0o0000, // Return address
+		0o221: 0o7001, // IAC
+		0o222: 0o5620, // JMP I 220
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cs := NewCallStack()
+	p.AddObserver(cs)
+	b := BreakOnPC(0o221)
+	p.AddBreakpoint(b)
+
+	stop, err := p.RunUntil(Halted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Breakpoint != b {
+		t.Fatalf("got breakpoint: %v, want: %v", stop.Breakpoint, b)
+	}
+	wantFrames := []Frame{
+		{Entry: 0o220, CallPC: 0o211},
+		{Entry: 0o210, CallPC: 0o200},
+	}
+	if got := cs.Frames(); !reflect.DeepEqual(got, wantFrames) {
+		t.Errorf("got frames: %v, want: %v", got, wantFrames)
+	}
+	wantString := "sub_00220 called from 00211\n" +
+		"sub_00210 called from 00200\n" +
+		"main"
+	if cs.String() != wantString {
+		t.Errorf("got:\n%s\nwant:\n%s", cs.String(), wantString)
+	}
+
+	p.RemoveBreakpoint(b)
+	stop, err = p.RunUntil(Halted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stop.Halted || len(cs.Frames()) != 0 {
+		t.Errorf("got halted: %t, frames: %v, want: halted: true, frames: []",
+			stop.Halted, cs.Frames())
+	}
+
+	out := &bytes.Buffer{}
+	if err := cs.WriteDOT(out); err != nil {
+		t.Fatal(err)
+	}
+	wantDOT := "digraph calls {\n" +
+		"  \"main\" -> \"sub_00210\" [label=\"2\"];\n" +
+		"  \"sub_00210\" -> \"sub_00220\" [label=\"2\"];\n" +
+		"}\n"
+	if out.String() != wantDOT {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), wantDOT)
+	}
+}
+
+func TestCallStack_interrupt(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o000: 0o0000, // Return address
+		0o001: 0o6042, // TCF
+		0o002: 0o6001, // ION
+		0o003: 0o5400, // JMP I 0
+		0o200: 0o6001, // ION
+		0o201: 0o6046, // TLS
+		0o202: 0o5202, // JMP 202
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cs := NewCallStack()
+	p.AddObserver(cs)
+
+	if _, err := p.RunUntil(InterruptTaken(), CycleBudget(500)); err != nil {
+		t.Fatal(err)
+	}
+	want := []Frame{{Entry: 0, CallPC: 0o202, Interrupt: true}}
+	if got := cs.Frames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got frames: %v, want: %v", got, want)
+	}
+	if _, err := p.RunUntil(PCEquals(0o202), CycleBudget(500)); err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.Frames(); len(got) != 0 {
+		t.Errorf("got frames: %v, want: []", got)
+	}
+}
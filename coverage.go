@@ -0,0 +1,169 @@
+/*
+ * Coverage of programs running on the emulator
+ *
+ * This records how many times each address is executed and, for
+ * instructions that can skip, how many times the skip was taken and
+ * not taken.  Instructions that can skip are ISZ, group 2 operate
+ * instructions that test AC or L, and IOTs.  Which IOTs skip depends
+ * on the device attached, so an IOT is only counted as a skip once
+ * it has skipped and the times it wasn't taken are the rest of the
+ * times it was executed.  Whether an address holds a skip comes from
+ * the instructions executed there, so that self-modifying code and
+ * overlays are counted correctly, and only comes from memory for
+ * addresses that haven't been executed.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Coverage is an Observer which records which instructions have been
+// executed.  It is attached with AddObserver.
+type Coverage struct {
+	NopObserver
+	p        *PDP8
+	counts   []uint64
+	branches map[uint]*coverageBranch // Skips by address
+}
+
+// coverageBranch records the skips executed at an address
+type coverageBranch struct {
+	taken    uint64
+	notTaken uint64
+	skip     bool // Whether an instruction that can skip has been executed
+}
+
+// CoverageEntry describes the coverage of an address
+type CoverageEntry struct {
+	Addr     uint   // The address, bits 12-14 are the field
+	Count    uint64 // The number of times executed
+	Skip     bool   // Whether it is an instruction that can skip
+	Taken    uint64 // The number of times the skip was taken
+	NotTaken uint64 // The number of times the skip wasn't taken
+}
+
+// NewCoverage returns a Coverage for p
+func NewCoverage(p *PDP8) *Coverage {
+	return &Coverage{
+		p:        p,
+		counts:   make([]uint64, len(p.mem)),
+		branches: map[uint]*coverageBranch{},
+	}
+}
+
+// canSkip returns whether ir is an instruction other than an IOT
+// that can skip
+func canSkip(ir uint) bool {
+	switch ir >> 9 {
+	case 2: // ISZ
+		return true
+	case 7: // Group 2 with a test of AC or L
+		return (ir&0o401) == 0o400 && (ir&0o160) != 0
+	}
+	return false
+}
+
+// isIOT returns whether ir is an IOT
+func isIOT(ir uint) bool {
+	return ir>>9 == 6
+}
+
+func (c *Coverage) InstructionExecuted(pc uint, ir uint, ea uint) {
+	if pc >= uint(len(c.counts)) {
+		return
+	}
+	c.counts[pc]++
+	iot := isIOT(ir)
+	if !iot && !canSkip(ir) {
+		return
+	}
+	// Interrupts are checked after this so the PC is still that
+	// set by the instruction
+	taken := c.p.pc == mask(pc+2)
+	b, ok := c.branches[pc]
+	if !ok {
+		b = &coverageBranch{}
+		c.branches[pc] = b
+	}
+	if taken {
+		b.taken++
+	} else {
+		b.notTaken++
+	}
+	if taken || !iot {
+		b.skip = true
+	}
+}
+
+// Entry returns the coverage of addr
+func (c *Coverage) Entry(addr uint) CoverageEntry {
+	e := CoverageEntry{Addr: addr}
+	if addr < uint(len(c.counts)) {
+		e.Count = c.counts[addr]
+		if e.Count == 0 {
+			e.Skip = canSkip(c.p.mem[addr])
+		}
+	}
+	if b, ok := c.branches[addr]; ok && b.skip {
+		e.Skip = true
+		e.Taken, e.NotTaken = b.taken, b.notTaken
+	}
+	return e
+}
+
+// Entries returns the coverage of each address that has been executed
+func (c *Coverage) Entries() []CoverageEntry {
+	var r []CoverageEntry
+	for addr, n := range c.counts {
+		if n > 0 {
+			r = append(r, c.Entry(uint(addr)))
+		}
+	}
+	return r
+}
+
+// WriteReport writes a disassembly of the non-zero words in memory
+// from start to end, inclusive, annotated with coverage.  Lines that
+// weren't executed are marked with #####.  Skips are followed by the
+// number of times taken and not taken, with any never taken or never
+// not taken marked with !.
+func (c *Coverage) WriteReport(w io.Writer, start uint, end uint) error {
+	var executed, total, skipsCovered, skips int
+	bw := bufio.NewWriter(w)
+	for addr := start; addr <= end && addr < uint(len(c.p.mem)); addr++ {
+		v := c.p.mem[addr]
+		e := c.Entry(addr)
+		if v == 0 && e.Count == 0 {
+			continue
+		}
+		total++
+		count := "#####"
+		if e.Count > 0 {
+			executed++
+			count = fmt.Sprint(e.Count)
+		}
+		line := fmt.Sprintf("%10s  %05o  %04o  %-16s", count, addr, v, c.p.Disassemble(addr, v))
+		if e.Skip {
+			skips++
+			line += fmt.Sprintf("  skip taken: %d, not taken: %d", e.Taken, e.NotTaken)
+			if e.Taken > 0 && e.NotTaken > 0 {
+				skipsCovered++
+			} else {
+				line += " !"
+			}
+		}
+		fmt.Fprintln(bw, strings.TrimRight(line, " "))
+	}
+	fmt.Fprintf(bw, "Executed %d of %d words, %d of %d skips both ways\n",
+		executed, total, skipsCovered, skips)
+	return bw.Flush()
+}
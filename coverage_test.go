@@ -0,0 +1,143 @@
+package pdp8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o2210, // ISZ 210
+		0o201: 0o5200, // JMP 200
+		0o202: 0o7440, // SZA
+		0o203: 0o7402, // HLT
+		0o204: 0o7402, // HLT
+		0o210: 0o7775,
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	c := NewCoverage(p)
+	p.AddObserver(c)
+
+	hlt, _, err := p.Run(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hlt {
+		t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
+	}
+
+	want := CoverageEntry{Addr: 0o200, Count: 3, Skip: true, Taken: 1, NotTaken: 2}
+	if got := c.Entry(0o200); got != want {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+
+	out := &bytes.Buffer{}
+	if err := c.WriteReport(out, 0o200, 0o204); err != nil {
+		t.Fatal(err)
+	}
+	wantReport := "" +
+		"         3  00200  2210  ISZ 210           skip taken: 1, not taken: 2\n" +
+		"         2  00201  5200  JMP 200\n" +
+		"         1  00202  7440  SZA               skip taken: 1, not taken: 0 !\n" +
+		"     #####  00203  7402  HLT\n" +
+		"         1  00204  7402  HLT\n" +
+		"Executed 4 of 5 words, 1 of 2 skips both ways\n"
+	if out.String() != wantReport {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), wantReport)
+	}
+}
+
+func TestCoverage_IOT(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6041, // TSF
+		0o201: 0o5204, // JMP 204
+		0o202: 0o6031, // KSF
+		0o203: 0o7402, // HLT
+		0o204: 0o6046, // TLS
+		0o205: 0o5200, // JMP 200
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	c := NewCoverage(p)
+	p.AddObserver(c)
+
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+
+	// TLS can't skip and KSF didn't
+	for _, addr := range []uint{0o202, 0o204} {
+		if e := c.Entry(addr); e.Skip || e.Count != 1 {
+			t.Errorf("got: %+v, want: a count of 1 and no skip", e)
+		}
+	}
+	e := c.Entry(0o200)
+	if !e.Skip || e.Taken != 1 || e.NotTaken == 0 || e.Count != e.Taken+e.NotTaken {
+		t.Errorf("got: %+v, want: skip taken once and not taken the rest", e)
+	}
+}
+
+func TestCoverage_selfModifying(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o2210, // ISZ 210
+		0o201: 0o7402, // HLT
+		0o202: 0o1211, // TAD 211
+		0o203: 0o3200, // DCA 200
+		0o204: 0o7000, // NOP
+		0o205: 0o1212, // TAD 212
+		0o206: 0o3204, // DCA 204
+		0o207: 0o7402, // HLT
+		0o210: 0o7777,
+		0o211: 0o7000, // NOP
+		0o212: 0o2213, // ISZ 213
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	c := NewCoverage(p)
+	p.AddObserver(c)
+
+	if _, err := p.RunUntil(Halted(), CycleBudget(100)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The skips are those of the instructions executed, not those
+	// now in memory
+	want := []CoverageEntry{
+		{Addr: 0o200, Count: 1, Skip: true, Taken: 1},
+		{Addr: 0o204, Count: 1},
+	}
+	for _, w := range want {
+		if got := c.Entry(w.Addr); got != w {
+			t.Errorf("got: %+v, want: %+v", got, w)
+		}
+	}
+}
@@ -12,16 +12,58 @@
 // TODO: Put in separate package?
 package pdp8
 
-type device interface {
-	// TODO: Export these methods?
-	// Is an interrupt raised?
-	// TODO: Rename to isInterrupt() ?
-	interrupt() (bool, error)
-	// Returns PC, LAC, error
-	iot(ir uint, pc uint, lac uint) (uint, uint, error)
-	// Return a slice of device numbers for the device
-	deviceNumbers() []int
+// Device is a peripheral accessed with IOT instructions
+type Device interface {
+	// Interrupt returns whether the device is requesting an interrupt
+	Interrupt() (bool, error)
+	// IOT executes the IOT instruction ir and returns the new PC and
+	// LAC.  The device field of ir is the device number the device
+	// expects even if it has been attached at another.
+	IOT(ir uint, pc uint, lac uint) (uint, uint, error)
+	// DeviceNumbers returns the device numbers the device expects
+	DeviceNumbers() []int
+	// Reset puts the device in the state it is in after power on
+	Reset()
+	// CAF clears the device's flags when a CAF instruction is executed
+	CAF()
+	// Info describes the device
+	Info() DeviceInfo
 	// Close the device when finished with
 	// TODO: Check if close best name
 	Close() error
 }
+
+// DeviceInfo describes a device
+type DeviceInfo struct {
+	Name        string // A short name such as TTY
+	Description string
+}
+
+// Attacher is implemented by devices which need the machine they are
+// attached to, such as to time operations with Cycles.  Attach is
+// called when the device is attached and with nil when it is detached.
+type Attacher interface {
+	Attach(p *PDP8)
+}
+
+// IOTNamer is implemented by devices whose IOTs have mnemonics which
+// Disassemble doesn't know, such as those sharing device numbers with
+// another device.  The device field of ir is the device number the
+// device expects.
+type IOTNamer interface {
+	IOTName(ir uint) (string, bool)
+}
+
+// instructionsPerSecond is used to convert the speed of a device into
+// cycles as the emulator counts instructions rather than time.  This
+// assumes an average of 2.5µs an instruction.
+const instructionsPerSecond = 400000
+
+// attachedCycles returns the cycles executed by p, the machine a device
+// is attached to, or 0 if the device isn't attached
+func attachedCycles(p *PDP8) uint64 {
+	if p == nil {
+		return 0
+	}
+	return p.Cycles()
+}
@@ -0,0 +1,177 @@
+/*
+ * A DF32 fixed head disk with up to four platters
+ *
+ * The disk is devices 60-62.  Each platter holds 32K words in 16
+ * tracks of 2048 words.  The timing and transfers are those of the
+ * fixed head disk shared with the RF08.  The disk address is confirmed
+ * as each word passes under the heads and the photocell marks the
+ * start of each revolution.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+const (
+	dfMaxPlatters  = 4
+	dfPlatterWords = 32768
+)
+
+// Extended address register
+const (
+	dfEmaPhotocell = 0o4000 // At the photocell mark
+	dfEmaDiskAddr  = 0o3700 // Bits 12-16 of the disk address
+	dfEmaField     = 0o70   // The memory field
+	dfErrDataLate  = 0o4    // Data late, not emulated
+	dfErrWriteLock = 0o2    // Write to a write protected platter
+	dfErrNoDisk    = 0o1    // Address on a platter that doesn't exist
+)
+
+type DF32 struct {
+	fixedHeadDisk      // The disk address is 17 bits
+	field         uint // Memory field
+}
+
+// NewDF32 returns a disk with no image attached
+func NewDF32() *DF32 {
+	df := &DF32{}
+	df.fixedHeadDisk = newFixedHeadDisk(df, "DF32", "platters",
+		dfMaxPlatters, dfPlatterWords, dfPlatterWords)
+	return df
+}
+
+// AttachImage attaches image holding the number of platters passed
+func (df *DF32) AttachImage(image DiskImage, platters int, writeProtect bool) error {
+	return df.attachImage(image, platters, writeProtect)
+}
+
+// OpenImage opens the image file filename and attaches it.  If the
+// file doesn't exist it is created unless writeProtect is set.  The
+// file is closed when detached.
+func (df *DF32) OpenImage(filename string, platters int, writeProtect bool) error {
+	return df.openImage(filename, platters, writeProtect)
+}
+
+// DetachImage detaches the disk image
+func (df *DF32) DetachImage() error {
+	return df.detachImage()
+}
+
+// SetWriteProtect sets the write lock switch of platter
+func (df *DF32) SetWriteProtect(platter int, on bool) {
+	if platter >= 0 && platter < dfMaxPlatters {
+		df.writeProtect[platter] = on
+	}
+}
+
+// Attach also cancels any transfer in progress
+func (df *DF32) Attach(p *PDP8) {
+	df.attach(p)
+}
+
+// Closes any image file opened by OpenImage
+func (df *DF32) Close() error {
+	return df.DetachImage()
+}
+
+func (df *DF32) DeviceNumbers() []int {
+	return []int{0o60, 0o61, 0o62}
+}
+
+func (df *DF32) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "DF32",
+		Description: "Fixed head disk",
+	}
+}
+
+// Reset clears the disk
+func (df *DF32) Reset() {
+	df.CAF()
+}
+
+// CAF clears the registers and flags and cancels any transfer
+func (df *DF32) CAF() {
+	df.da = 0
+	df.field = 0
+	df.clearFlags()
+}
+
+// Interrupt returns if the completion or an error flag is set
+func (df *DF32) Interrupt() (bool, error) {
+	return df.done || df.errors() != 0, nil
+}
+
+// IOT returns PC, LAC, error
+func (df *DF32) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	link := lac & 0o10000
+	ac := mask(lac)
+	device := (ir >> 3) & 0o77
+	switch device {
+	case 0o60:
+		if (ir & 0o1) == 0o1 { // DCMA - Clear disk address and flags
+			df.da &^= 0o7777
+			df.clearFlags()
+		}
+		if (ir & 0o6) != 0 { // DMAR/DMAW - Load disk address and go
+			df.da |= ac
+			ac = 0
+			df.start((ir&0o4) == 0o4, df.field)
+		}
+	case 0o61:
+		switch ir & 0o7 {
+		case 0o1: // DCEA - Clear extended address
+			df.da &= 0o7777
+			df.field = 0
+		case 0o2: // DSAC - Skip on address confirmed
+			// Unlike the RF08 the AC is left alone
+			if df.position() == df.da%fhTrackWords {
+				pc = mask(pc + 1)
+			}
+		case 0o5: // DEAL - Load extended address
+			df.da &= 0o7777
+			df.da |= (ac & dfEmaDiskAddr) << 6
+			df.field = (ac & dfEmaField) >> 3
+			ac = 0
+		case 0o6: // DEAC - Read extended address
+			ac = df.ema()
+		}
+	case 0o62:
+		switch ir & 0o7 {
+		case 0o1: // DFSE - Skip on no errors
+			if df.errors() == 0 {
+				pc = mask(pc + 1)
+			}
+		case 0o2: // DFSC - Skip on completion
+			if df.done {
+				pc = mask(pc + 1)
+			}
+		case 0o6: // DMAC - Read disk address
+			ac = df.da & 0o7777
+		}
+	}
+	return pc, link | ac, nil
+}
+
+// ema returns the extended address register
+func (df *DF32) ema() uint {
+	r := (df.da>>6)&dfEmaDiskAddr | df.field<<3 | df.errors()
+	if df.position() == 0 {
+		r |= dfEmaPhotocell
+	}
+	return r
+}
+
+// errors returns the error flags
+func (df *DF32) errors() uint {
+	var r uint
+	if df.writeLock {
+		r |= dfErrWriteLock
+	}
+	if df.noDisk {
+		r |= dfErrNoDisk
+	}
+	return r
+}
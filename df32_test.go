@@ -0,0 +1,160 @@
+package pdp8
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// dfRoutine returns a routine which loads the extended address with
+// ema, starts a transfer with the disk address da using the IOT
+// start, waits for completion and then halts at 0210 if there is an
+// error, otherwise at 0211
+func dfRoutine(ema uint, da uint, start uint) map[uint]uint {
+	return map[uint]uint{
+		0o200: 0o1220, // TAD 220
+		0o201: 0o6615, // DEAL
+		0o202: 0o1221, // TAD 221
+		0o203: start,
+		0o204: 0o6622, // DFSC
+		0o205: 0o5204, // JMP 204
+		0o206: 0o6621, // DFSE
+		0o207: 0o7402, // HLT
+		0o210: 0o7402, // HLT
+		0o220: ema,
+		0o221: da,
+	}
+}
+
+func TestDF32_write_read(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.df32")
+	df := NewDF32()
+	defer df.Close()
+	if err := df.OpenImage(filename, 2, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write 20 words from field 1 to platter 1
+	p := newDeviceMachine(t, df, dfRoutine(0o1010, 0o5, 0o6605),
+		map[uint]uint{fhWCAddr: 0o7760, fhWCAddr + 1: 0o777})
+	for i := uint(0); i < 0o20; i++ {
+		p.mem[0o11000+i] = 0o3000 + i
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o211 {
+		t.Fatalf("write - got PC: %04o, errors: %o", p.pc, df.errors())
+	}
+	if p.mem[0o7750] != 0 || p.mem[0o7751] != 0o1017 {
+		t.Errorf("got word count: %04o, current address: %04o",
+			p.mem[0o7750], p.mem[0o7751])
+	}
+	if df.da != 0o100025 {
+		t.Errorf("got disk address: %06o, want: 100025", df.da)
+	}
+	if want := uint64(0o20 * fhRevCycles / fhTrackWords); p.Cycles() < want {
+		t.Errorf("got cycles: %d, want at least: %d", p.Cycles(), want)
+	}
+	words := make([]uint, 0o20)
+	if err := readWords(df.image, 0o100005, words); err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range words {
+		if w != 0o3000+uint(i) {
+			t.Fatalf("disk word %o got: %04o, want: %04o", i, w, 0o3000+i)
+		}
+	}
+
+	// Read them back to field 0
+	p = newDeviceMachine(t, df, dfRoutine(0o1000, 0o5, 0o6603),
+		map[uint]uint{fhWCAddr: 0o7760, fhWCAddr + 1: 0o1777})
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o211 {
+		t.Fatalf("read - got PC: %04o, errors: %o", p.pc, df.errors())
+	}
+	for i := uint(0); i < 0o20; i++ {
+		if got, want := p.mem[0o2000+i], 0o3000+i; got != want {
+			t.Fatalf("mem[%05o] got: %04o, want: %04o", 0o2000+i, got, want)
+		}
+	}
+}
+
+func TestDF32_errors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.df32")
+	cases := []struct {
+		name  string
+		ema   uint
+		start uint
+		want  uint
+	}{
+		{name: "no platter", ema: 0o1000, start: 0o6603, want: dfErrNoDisk},
+		{name: "write protected", ema: 0, start: 0o6605, want: dfErrWriteLock},
+	}
+	for _, c := range cases {
+		df := NewDF32()
+		if err := df.OpenImage(filename, 1, false); err != nil {
+			t.Fatal(err)
+		}
+		df.SetWriteProtect(0, true)
+		p := newDeviceMachine(t, df, dfRoutine(c.ema, 0, c.start),
+			map[uint]uint{fhWCAddr: 0o7777, fhWCAddr + 1: 0o777})
+		if _, err := p.RunUntil(Halted(), CycleBudget(100)); err != nil {
+			t.Fatal(err)
+		}
+		if p.pc != 0o210 || df.errors() != c.want {
+			t.Errorf("%s - got PC: %04o, errors: %o, want errors: %o",
+				c.name, p.pc, df.errors(), c.want)
+		}
+		if err := df.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDF32_photocell(t *testing.T) {
+	df := NewDF32()
+	p := newDeviceMachine(t, df, nil, nil)
+	if (df.ema() & dfEmaPhotocell) == 0 {
+		t.Error("photocell not set at start of revolution")
+	}
+
+	// Wait for the address to be confirmed
+	df.da = 0o1234
+	p.mem[0o200] = 0o6612 // DSAC
+	p.mem[0o201] = 0o5200 // JMP 200
+	p.mem[0o202] = 0o7402 // HLT
+	if _, err := p.RunUntil(Halted(), CycleBudget(fhRevCycles)); err != nil {
+		t.Fatal(err)
+	}
+	if (df.ema() & dfEmaPhotocell) != 0 {
+		t.Error("photocell set away from start of revolution")
+	}
+	if df.position() != 0o1234 {
+		t.Errorf("got position: %04o, want: 1234", df.position())
+	}
+}
+
+func TestDF32_DEAC(t *testing.T) {
+	df := NewDF32()
+	newDeviceMachine(t, df, nil, nil)
+
+	// At the address DSAC skips, leaving the AC, but DEAC doesn't
+	df.da = df.position()
+	if pc, lac, _ := df.IOT(0o6612, 0o200, 0o1234); pc != 0o201 || lac != 0o1234 {
+		t.Errorf("DSAC - got PC: %04o, AC: %04o, want PC: 0201, AC: 1234", pc, lac)
+	}
+	df.da = 0o31234
+	df.field = 0o2
+	pc, lac, _ := df.IOT(0o6616, 0o200, 0)
+	if pc != 0o200 {
+		t.Errorf("DEAC - got PC: %04o, want: 0200", pc)
+	}
+	if want := df.ema(); lac != want {
+		t.Errorf("DEAC - got AC: %04o, want: %04o", lac, want)
+	}
+	if df.da != 0o31234 || df.field != 0o2 {
+		t.Errorf("DEAC - got disk address: %05o, field: %o", df.da, df.field)
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ * A disassembler
+ *
+ * This uses the same mnemonics and layout as SIMH so that traces can
+ * be compared with it.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"fmt"
+	"strings"
+)
+
+var mriNames = [6]string{"AND", "TAD", "ISZ", "DCA", "JMS", "JMP"}
+
+// iotNames are the mnemonics for IOTs that aren't memory extension
+// instructions
+var iotNames = map[uint]string{
+	0o6001: "ION",
+	0o6002: "IOF",
+	0o6007: "CAF",
+	0o6010: "RPE",
+	0o6011: "RSF",
+	0o6012: "RRB",
+	0o6014: "RFC",
+	0o6016: "RRB RFC",
+	0o6020: "PCE",
+	0o6021: "PSF",
+	0o6022: "PCF",
+	0o6024: "PPC",
+	0o6026: "PLS",
+	0o6031: "KSF",
+	0o6032: "KCC",
+	0o6034: "KRS",
+	0o6036: "KRB",
+	0o6041: "TSF",
+	0o6042: "TCF",
+	0o6044: "TPC",
+	0o6046: "TLS",
+	0o6661: "PSKF",
+	0o6662: "PCLF",
+	0o6663: "PSKE",
+	0o6664: "PSTB",
+	0o6665: "PSIE",
+	0o6666: "PCLF PSTB",
+	0o6667: "PCIE",
+	0o6741: "DSKP",
+	0o6742: "DCLR",
+	0o6743: "DLAG",
+	0o6744: "DLCA",
+	0o6745: "DRST",
+	0o6746: "DLDC",
+	0o6747: "DMAN",
+	0o6751: "LCD",
+	0o6752: "XDR",
+	0o6753: "STR",
+	0o6754: "SER",
+	0o6755: "SDN",
+	0o6756: "INTR",
+	0o6757: "INIT",
+	0o6761: "DTRA",
+	0o6762: "DTCA",
+	0o6764: "DTXA",
+	0o6766: "DTCA DTXA",
+	0o6771: "DTSF",
+	0o6772: "DTRB",
+	0o6774: "DTLB",
+}
+
+// oprNames are operate instructions that have their own mnemonic
+// rather than being shown as their microinstructions
+var oprNames = map[uint]string{
+	0o7000: "NOP",
+	0o7041: "CIA",
+	0o7120: "STL",
+	0o7204: "GLK",
+	0o7240: "STA",
+	0o7400: "NOP",
+	0o7401: "NOP",
+	0o7410: "SKP",
+	0o7604: "LAS",
+	0o7621: "CAM",
+}
+
+// Disassemble returns the mnemonic for the instruction ir at addr.
+// addr is needed to work out current page addresses.
+func Disassemble(addr uint, ir uint) string {
+	ir = mask(ir)
+	opCode := ir >> 9
+	switch {
+	case opCode <= 5:
+		return disassembleMRI(addr, ir)
+	case opCode == 6:
+		return disassembleIOT(ir)
+	}
+	if name, ok := oprNames[ir]; ok {
+		return name
+	}
+	var names []string
+	add := func(bits uint, name string) {
+		if ir&bits == bits {
+			names = append(names, name)
+		}
+	}
+	if (ir & 0o400) != 0o400 { // Group 1
+		add(0o200, "CLA")
+		add(0o100, "CLL")
+		if ir&0o41 == 0o41 {
+			names = append(names, "CIA")
+		} else {
+			add(0o40, "CMA")
+		}
+		add(0o20, "CML")
+		if ir&0o41 == 0o1 {
+			names = append(names, "IAC")
+		}
+		switch ir & 0o16 {
+		case 0o2:
+			names = append(names, "BSW")
+		case 0o4:
+			names = append(names, "RAL")
+		case 0o6:
+			names = append(names, "RTL")
+		case 0o10:
+			names = append(names, "RAR")
+		case 0o12:
+			names = append(names, "RTR")
+		case 0o14, 0o16:
+			names = append(names, "RAR RAL")
+		}
+	} else if (ir & 0o1) != 0o1 { // Group 2
+		if (ir & 0o10) == 0o10 {
+			add(0o100, "SPA")
+			add(0o40, "SNA")
+			add(0o20, "SZL")
+			if ir&0o160 == 0 {
+				names = append(names, "SKP")
+			}
+		} else {
+			add(0o100, "SMA")
+			add(0o40, "SZA")
+			add(0o20, "SNL")
+		}
+		add(0o200, "CLA")
+		add(0o4, "OSR")
+		add(0o2, "HLT")
+	} else { // Group 3
+		add(0o200, "CLA")
+		switch ir & 0o120 {
+		case 0o100:
+			names = append(names, "MQA")
+		case 0o20:
+			names = append(names, "MQL")
+		case 0o120:
+			names = append(names, "SWP")
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("%04o", ir)
+	}
+	return strings.Join(names, " ")
+}
+
+// Disassemble is like the package Disassemble except that IOTs are
+// named by the device attached at their device number.  This keeps a
+// device's mnemonics if it is attached at other device numbers or
+// shares its device numbers with another device.
+func (p *PDP8) Disassemble(addr uint, ir uint) string {
+	ir = mask(ir)
+	slot := p.iotDevices[(ir>>3)&0o77]
+	if ir>>9 != 6 || slot.a == nil {
+		return Disassemble(addr, ir)
+	}
+	native := (ir &^ 0o770) | slot.native<<3
+	if n, ok := slot.a.d.(IOTNamer); ok {
+		if name, ok := n.IOTName(native); ok {
+			return name
+		}
+	}
+	if name, ok := iotNames[native]; ok {
+		return name
+	}
+	return Disassemble(addr, ir)
+}
+
+func disassembleMRI(addr uint, ir uint) string {
+	opAddr := ir & 0o177
+	if (ir & 0o200) == 0o200 { // If current page
+		opAddr |= addr & 0o7600
+	}
+	indirect := " "
+	if (ir & 0o400) == 0o400 {
+		indirect = " I "
+	}
+	return fmt.Sprintf("%s%s%o", mriNames[ir>>9], indirect, opAddr)
+}
+
+func disassembleIOT(ir uint) string {
+	device := (ir >> 3) & 0o77
+	if device >= 0o20 && device <= 0o27 {
+		field := (ir >> 3) & 0o7
+		switch ir & 0o7 {
+		case 0o1:
+			return fmt.Sprintf("CDF %o0", field)
+		case 0o2:
+			return fmt.Sprintf("CIF %o0", field)
+		case 0o3:
+			return fmt.Sprintf("CDF CIF %o0", field)
+		}
+		switch ir {
+		case 0o6214:
+			return "RDF"
+		case 0o6224:
+			return "RIF"
+		case 0o6234:
+			return "RIB"
+		case 0o6244:
+			return "RMF"
+		}
+	}
+	if name, ok := iotNames[ir]; ok {
+		return name
+	}
+	return fmt.Sprintf("IOT %04o", ir)
+}
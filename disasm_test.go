@@ -0,0 +1,68 @@
+package pdp8
+
+import "testing"
+
+func TestDisassemble(t *testing.T) {
+	cases := []struct {
+		addr uint
+		ir   uint
+		want string
+	}{
+		{0o200, 0o1210, "TAD 210"},
+		{0o200, 0o1010, "TAD 10"},
+		{0o1200, 0o3610, "DCA I 1210"},
+		{0o200, 0o5200, "JMP 200"},
+		{0o200, 0o6046, "TLS"},
+		{0o200, 0o6213, "CDF CIF 10"},
+		{0o200, 0o6214, "RDF"},
+		{0o200, 0o6741, "DSKP"},
+		{0o200, 0o6541, "IOT 6541"},
+		{0o200, 0o7000, "NOP"},
+		{0o200, 0o7041, "CIA"},
+		{0o200, 0o7300, "CLA CLL"},
+		{0o200, 0o7104, "CLL RAL"},
+		{0o200, 0o7002, "BSW"},
+		{0o200, 0o7450, "SNA"},
+		{0o200, 0o7640, "SZA CLA"},
+		{0o200, 0o7402, "HLT"},
+		{0o200, 0o7410, "SKP"},
+		{0o200, 0o7421, "MQL"},
+		{0o200, 0o7521, "SWP"},
+	}
+	for _, c := range cases {
+		if got := Disassemble(c.addr, c.ir); got != c.want {
+			t.Errorf("Disassemble(%04o, %04o) got: %q, want: %q",
+				c.addr, c.ir, got, c.want)
+		}
+	}
+}
+
+func TestPDP8_Disassemble(t *testing.T) {
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+	td := NewTD8E()
+	defer td.Close()
+	p, err := New(WithDevice(tty, 0o40, 0o41), WithDevice(td))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		ir   uint
+		want string
+	}{
+		{0o6416, "TLS"},
+		{0o6401, "KSF"},
+		{0o6046, "TLS"},
+		{0o6771, "SDSS"},
+		{0o6774, "SDLC"},
+		{0o6741, "DSKP"},
+		{0o1210, "TAD 210"},
+	}
+	for _, c := range cases {
+		if got := p.Disassemble(0o200, c.ir); got != c.want {
+			t.Errorf("Disassemble(0200, %04o) got: %q, want: %q",
+				c.ir, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * Disk images
+ *
+ * Mass storage devices store their contents in image files in the
+ * same format as SIMH.  Each 12-bit word is held in a 16-bit little
+ * endian word.  Parts of an image beyond the end of the file read as
+ * 0 so that an empty file can be used as a blank disk.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// DiskImage is the image of a disk, DECtape or diskette.  An *os.File
+// can be used.
+type DiskImage interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// openImage opens the image file filename, read only if readOnly
+func openImage(filename string, readOnly bool) (*os.File, error) {
+	if readOnly {
+		return os.Open(filename)
+	}
+	return os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0o644)
+}
+
+// readWords reads len(words) words from image starting at word pos
+func readWords(image DiskImage, pos int64, words []uint) error {
+	buf := make([]byte, len(words)*2)
+	n, err := image.ReadAt(buf, pos*2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	for i := n; i < len(buf); i++ {
+		buf[i] = 0
+	}
+	for i := range words {
+		words[i] = mask(uint(buf[i*2]) | uint(buf[i*2+1])<<8)
+	}
+	return nil
+}
+
+// writeWords writes words to image starting at word pos
+func writeWords(image DiskImage, pos int64, words []uint) error {
+	buf := make([]byte, len(words)*2)
+	for i, w := range words {
+		buf[i*2] = byte(w)
+		buf[i*2+1] = byte(w>>8) & 0o17
+	}
+	_, err := image.WriteAt(buf, pos*2)
+	return err
+}
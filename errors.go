@@ -0,0 +1,94 @@
+/*
+ * Error types
+ *
+ * These can be inspected using errors.As to find out more about
+ * what went wrong.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import "fmt"
+
+// HaltError is returned when a HLT is executed unexpectedly
+type HaltError struct {
+	PC uint // Address of the HLT instruction
+}
+
+func (e *HaltError) Error() string {
+	return fmt.Sprintf("HLT at PC: %04o", e.PC)
+}
+
+// ChecksumError is returned when the checksum of a tape is wrong
+type ChecksumError struct {
+	Got  uint // The checksum calculated from the tape
+	Want uint // The checksum recorded on the tape
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum fail: %04o, should be: %04o", e.Got, e.Want)
+}
+
+// DeviceError is returned when a device reports an error
+type DeviceError struct {
+	Device uint  // The device number
+	PC     uint  // Address of the instruction being executed
+	IR     uint  // The instruction being executed
+	Err    error // The error reported by the device
+}
+
+func (e *DeviceError) Error() string {
+	return fmt.Sprintf("device %02o, PC: %04o, IR: %04o: %s",
+		e.Device, e.PC, e.IR, e.Err)
+}
+
+func (e *DeviceError) Unwrap() error {
+	return e.Err
+}
+
+// LoaderError is returned when a tape fails to load
+type LoaderError struct {
+	Pos int   // The position on the tape, starting at 0
+	Err error // The reason the tape failed to load
+}
+
+func (e *LoaderError) Error() string {
+	return fmt.Sprintf("loader failed at tape position %d: %s", e.Pos, e.Err)
+}
+
+func (e *LoaderError) Unwrap() error {
+	return e.Err
+}
+
+// ReplayError is returned when a replayed session diverges from the
+// one that was recorded.  If the session ended without all the input
+// being consumed, Unconsumed is the number of inputs left and the
+// rest describes the first of them.
+type ReplayError struct {
+	Stream     string // The stream being replayed
+	Cycle      uint64 // The cycle the input was consumed at
+	Want       uint64 // The cycle the input was recorded at
+	Unconsumed int    // The number of inputs that weren't consumed
+}
+
+func (e *ReplayError) Error() string {
+	if e.Unconsumed > 0 {
+		return fmt.Sprintf("replay ended at cycle %d with %d inputs not consumed, first on stream %q recorded at cycle %d",
+			e.Cycle, e.Unconsumed, e.Stream, e.Want)
+	}
+	return fmt.Sprintf("replay diverged on stream %q: input consumed at cycle %d, recorded at cycle %d",
+		e.Stream, e.Cycle, e.Want)
+}
+
+// BreakError is returned by Run when a breakpoint is hit
+type BreakError struct {
+	Breakpoint *Breakpoint // The breakpoint that was hit
+	PC         uint        // The address of the next instruction
+}
+
+func (e *BreakError) Error() string {
+	return fmt.Sprintf("breakpoint %s hit, PC: %05o", e.Breakpoint, e.PC)
+}
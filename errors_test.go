@@ -0,0 +1,169 @@
+package pdp8
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A device that returns an error for every IOT and interrupt check
+type failingDevice struct {
+	err           error
+	failInterrupt bool
+}
+
+func (d *failingDevice) Interrupt() (bool, error) {
+	if d.failInterrupt {
+		return false, d.err
+	}
+	return false, nil
+}
+
+func (d *failingDevice) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	return pc, lac, d.err
+}
+
+func (d *failingDevice) DeviceNumbers() []int {
+	return []int{0o50}
+}
+
+func (d *failingDevice) Reset() {}
+func (d *failingDevice) CAF()   {}
+
+func (d *failingDevice) Info() DeviceInfo {
+	return DeviceInfo{Name: "FAIL"}
+}
+
+func (d *failingDevice) Close() error {
+	return nil
+}
+
+func TestRun_DeviceError_iot(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o6501, // IOT 50
+		0o202: 0o7402, // HLT
+	}
+
+	deviceErr := errors.New("device failed")
+	p, err := New(WithDevice(&failingDevice{err: deviceErr}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	_, _, err = p.Run(500)
+	var de *DeviceError
+	if !errors.As(err, &de) {
+		t.Fatalf("got error: %v, want: *DeviceError", err)
+	}
+	if de.Device != 0o50 || de.PC != 0o201 || de.IR != 0o6501 {
+		t.Errorf("got: Device: %02o, PC: %04o, IR: %04o, want: Device: 50, PC: 0201, IR: 6501",
+			de.Device, de.PC, de.IR)
+	}
+	if !errors.Is(err, deviceErr) {
+		t.Errorf("errors.Is(%v, %v) is false", err, deviceErr)
+	}
+}
+
+func TestRun_DeviceError_interrupt(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6001, // ION
+		0o201: 0o7000, // NOP
+		0o202: 0o7402, // HLT
+	}
+
+	deviceErr := errors.New("device failed")
+	p, err := New(WithDevice(&failingDevice{err: deviceErr, failInterrupt: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	_, _, err = p.Run(500)
+	var de *DeviceError
+	if !errors.As(err, &de) {
+		t.Fatalf("got error: %v, want: *DeviceError", err)
+	}
+	if de.Device != 0o50 || de.PC != 0o202 {
+		t.Errorf("got: Device: %02o, PC: %04o, want: Device: 50, PC: 0202",
+			de.Device, de.PC)
+	}
+}
+
+func TestLoadRIMTape_HaltError(t *testing.T) {
+	// Stores a HLT over the start of the RIM loader
+	tape := []byte{
+		0o200, 0o200, // Leader
+		0o177, 0o056, // Address 7756
+		0o074, 0o002, // HLT
+		0o200, 0o200, // Trailer
+	}
+	filename := filepath.Join(t.TempDir(), "halt.rim")
+	if err := os.WriteFile(filename, tape, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.LoadRIMTape(tty, filename)
+	var le *LoaderError
+	if !errors.As(err, &le) {
+		t.Fatalf("got error: %v, want: *LoaderError", err)
+	}
+	if le.Pos != 6 {
+		t.Errorf("LoaderError - got Pos: %d, want: 6", le.Pos)
+	}
+	var he *HaltError
+	if !errors.As(err, &he) {
+		t.Fatalf("got error: %v, want: *HaltError", err)
+	}
+	if he.PC != 0o7756 {
+		t.Errorf("HaltError - got PC: %04o, want: 7756", he.PC)
+	}
+}
+
+func TestLoad_ChecksumError(t *testing.T) {
+	tape := []byte{
+		0o200, 0o200, // Leader
+		0o102, 0o000, // Origin 0200
+		0o012, 0o034, // 1234
+		0o000, 0o001, // Checksum which should be 0046
+		0o200, 0o200, // Trailer
+	}
+	filename := filepath.Join(t.TempDir(), "checksum.bin")
+	if err := os.WriteFile(filename, tape, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Load(filename)
+	var ce *ChecksumError
+	if !errors.As(err, &ce) {
+		t.Fatalf("got error: %v, want: *ChecksumError", err)
+	}
+	if ce.Got != 0o46 || ce.Want != 0o1 {
+		t.Errorf("got: Got: %04o, Want: %04o, want: Got: 0046, Want: 0001",
+			ce.Got, ce.Want)
+	}
+	if p.mem[0o200] != 0o1234 {
+		t.Errorf("got mem[0200]: %04o, want: 1234", p.mem[0o200])
+	}
+}
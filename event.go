@@ -0,0 +1,81 @@
+/*
+ * Events scheduled to run at a given cycle
+ *
+ * Events are run at the end of a cycle, after any interrupt has been
+ * taken, which is the same point at which Run returns.  This means
+ * that something done between calls to Run can be repeated exactly.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+type event struct {
+	owner any          // What scheduled the event
+	cycle uint64       // The cycle to run at
+	fn    func() error // The function to run
+	p     *PDP8        // The machine scheduled on, nil once run or cancelled
+}
+
+// schedule arranges for fn to be called at the end of the cycle
+// when Cycles() reaches cycle.  Events for the same cycle are
+// run in the order they were scheduled.  owner is what scheduled
+// the event so that its events can be dropped if it is removed.
+// The event returned can be used to cancel it.
+func (p *PDP8) schedule(owner any, cycle uint64, fn func() error) *event {
+	e := &event{owner: owner, cycle: cycle, fn: fn, p: p}
+	i := len(p.events)
+	for i > 0 && p.events[i-1].cycle > cycle {
+		i--
+	}
+	p.events = append(p.events, nil)
+	copy(p.events[i+1:], p.events[i:])
+	p.events[i] = e
+	return e
+}
+
+// cancel stops e from running.  It does nothing if e is nil or has
+// already been run or cancelled.
+func (e *event) cancel() {
+	if e == nil || e.p == nil {
+		return
+	}
+	p := e.p
+	e.p = nil
+	for i, ev := range p.events {
+		if ev == e {
+			p.events = append(p.events[:i], p.events[i+1:]...)
+			return
+		}
+	}
+}
+
+// cancelEvents cancels every event scheduled by owner
+func (p *PDP8) cancelEvents(owner any) {
+	events := p.events[:0]
+	for _, e := range p.events {
+		if e.owner == owner {
+			e.p = nil
+		} else {
+			events = append(events, e)
+		}
+	}
+	clear(p.events[len(events):])
+	p.events = events
+}
+
+// runEvents runs any events that are due.  err is returned in
+// preference to any error returned by the events.
+func (p *PDP8) runEvents(err error) error {
+	for len(p.events) > 0 && p.events[0].cycle <= p.cycles {
+		e := p.events[0]
+		p.events = p.events[1:]
+		e.p = nil
+		if evErr := e.fn(); err == nil {
+			err = evErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+/*
+ * The fixed head disk shared by the DF32 and RF08
+ *
+ * Both disks have a head for every track of 2048 words and turn at
+ * 1800 rpm.  A transfer starts once the disk has turned to the word
+ * addressed and then uses three cycle data breaks with the word count
+ * at 7750 and the current address at 7751.  A unit is a DF32 platter
+ * or an RS08 disk.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	fhTrackWords = 2048
+	fhRevCycles  = instructionsPerSecond / 30 // Cycles a revolution
+	fhWCAddr     = 0o7750                     // The word count, followed by the current address
+)
+
+// fixedHeadDisk is the image, timing and transfers of a fixed head
+// disk, which is embedded in the device
+type fixedHeadDisk struct {
+	d            Device // The device, which schedules the transfers
+	name         string // The name of the device used in errors
+	unitName     string // What a unit is called in errors
+	maxUnits     uint
+	unitWords    uint // Words in each unit
+	lockWords    uint // Words protected by each write lock switch
+	addressLimit uint // The disk address wraps at this
+
+	p            *PDP8 // The machine attached to
+	image        DiskImage
+	file         *os.File // The file opened by OpenImage, if any
+	units        uint
+	writeProtect []bool
+	da           uint   // Disk address
+	done         bool   // Completion flag
+	noDisk       bool   // Address on a unit that doesn't exist
+	writeLock    bool   // Write to a write protected address
+	pending      *event // The transfer in progress, nil if none
+}
+
+// newFixedHeadDisk returns a disk for d with up to maxUnits units
+func newFixedHeadDisk(d Device, name string, unitName string, maxUnits uint, unitWords uint, lockWords uint) fixedHeadDisk {
+	limit := maxUnits * unitWords
+	return fixedHeadDisk{
+		d:            d,
+		name:         name,
+		unitName:     unitName,
+		maxUnits:     maxUnits,
+		unitWords:    unitWords,
+		lockWords:    lockWords,
+		addressLimit: limit,
+		writeProtect: make([]bool, limit/lockWords),
+	}
+}
+
+// attachImage attaches image holding the number of units passed
+func (fh *fixedHeadDisk) attachImage(image DiskImage, units int, writeProtect bool) error {
+	if units < 1 || uint(units) > fh.maxUnits {
+		return fmt.Errorf("%s: invalid number of %s: %d", fh.name, fh.unitName, units)
+	}
+	if err := fh.detachImage(); err != nil {
+		return err
+	}
+	fh.image = image
+	fh.units = uint(units)
+	for i := range fh.writeProtect {
+		fh.writeProtect[i] = writeProtect
+	}
+	return nil
+}
+
+// openImage opens the image file filename and attaches it.  If the
+// file doesn't exist it is created unless writeProtect is set.
+func (fh *fixedHeadDisk) openImage(filename string, units int, writeProtect bool) error {
+	f, err := openImage(filename, writeProtect)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fh.name, err)
+	}
+	if err := fh.attachImage(f, units, writeProtect); err != nil {
+		f.Close()
+		return err
+	}
+	fh.file = f
+	return nil
+}
+
+// detachImage detaches the disk image and closes the file if it was
+// opened by openImage
+func (fh *fixedHeadDisk) detachImage() error {
+	var err error
+	if fh.file != nil {
+		err = fh.file.Close()
+	}
+	fh.image = nil
+	fh.file = nil
+	fh.units = 0
+	return err
+}
+
+// attach also cancels any transfer in progress
+func (fh *fixedHeadDisk) attach(p *PDP8) {
+	fh.p = p
+	fh.pending.cancel()
+}
+
+// clearFlags clears the completion and error flags and cancels any
+// transfer in progress
+func (fh *fixedHeadDisk) clearFlags() {
+	fh.done = false
+	fh.noDisk = false
+	fh.writeLock = false
+	fh.pending.cancel()
+}
+
+// position returns the word of the track under the heads
+func (fh *fixedHeadDisk) position() uint {
+	return uint(attachedCycles(fh.p) * fhTrackWords / fhRevCycles % fhTrackWords)
+}
+
+// start waits for the disk to turn to the disk address and then
+// reads or writes the number of words in the word count using field.
+// Completion is also set if there is an error.
+func (fh *fixedHeadDisk) start(write bool, field uint) {
+	if fh.image == nil || fh.da >= fh.units*fh.unitWords {
+		fh.noDisk = true
+		fh.done = true
+		return
+	}
+	if write && fh.writeProtect[fh.da/fh.lockWords] {
+		fh.writeLock = true
+		fh.done = true
+		return
+	}
+	n := 0o10000 - fh.p.DataBreakRead(fhWCAddr)
+	wait := (fh.da%fhTrackWords + fhTrackWords - fh.position()) % fhTrackWords
+	cycles := uint64(wait+n) * fhRevCycles / fhTrackWords
+	fh.pending.cancel()
+	fh.pending = fh.p.schedule(fh.d, attachedCycles(fh.p)+cycles, func() error {
+		err := fh.transfer(write, field)
+		fh.done = true
+		return err
+	})
+}
+
+// transfer reads or writes words using three cycle data breaks
+func (fh *fixedHeadDisk) transfer(write bool, field uint) error {
+	var err error
+	size := fh.units * fh.unitWords
+	word := make([]uint, 1)
+	fh.p.threeCycleBreak(fhWCAddr, field, func(addr uint) bool {
+		if fh.image == nil || fh.da >= size {
+			fh.noDisk = true
+			return false
+		}
+		if write {
+			if fh.writeProtect[fh.da/fh.lockWords] {
+				fh.writeLock = true
+				return false
+			}
+			word[0] = fh.p.DataBreakRead(addr)
+			err = writeWords(fh.image, int64(fh.da), word)
+		} else {
+			err = readWords(fh.image, int64(fh.da), word)
+			fh.p.DataBreakWrite(addr, word[0])
+		}
+		fh.da = (fh.da + 1) % fh.addressLimit
+		return err == nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fh.name, err)
+	}
+	return nil
+}
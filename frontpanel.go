@@ -0,0 +1,228 @@
+/*
+ * A front panel
+ *
+ * This models the switches and lights of a PDP-8/I front panel so
+ * that the procedures in the manuals can be followed.  The keys act
+ * immediately, apart from Start and Continue which set the machine
+ * running and Run which then executes instructions while it is.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import "sync/atomic"
+
+// Lights are the indicators on the front panel
+type Lights struct {
+	PC    uint       // Program counter
+	MA    uint       // Memory address of the last memory cycle
+	MB    uint       // Memory buffer, the word last read or written
+	AC    uint       // Accumulator
+	L     uint       // Link
+	MQ    uint       // Multiplier Quotient
+	IF    uint       // Instruction field
+	DF    uint       // Data field
+	IR    uint       // Instruction register, the opcode of the instruction
+	State MajorState // The major state of the next memory cycle
+	ION   bool       // Whether interrupts are enabled
+	Run   bool       // Whether the machine is running
+}
+
+// FrontPanel is the front panel of a machine.  It is an Observer so
+// that it can show the memory address and buffer as it runs.
+type FrontPanel struct {
+	NopObserver
+	p                 *PDP8
+	instField         uint // Extended address switches
+	dataField         uint // Data field switches
+	singleStep        bool
+	singleInstruction bool
+	ma, mb            uint
+	running           atomic.Bool
+
+	// Used to step through the major states of an instruction
+	recording bool          // Whether recording the memory cycles of an instruction
+	pending   []MemoryCycle // Memory cycles yet to be shown
+	last      MemoryCycle   // The memory cycle last shown
+	ir        uint          // The instruction being recorded
+	deferAddr uint          // The address of an indirect address to be read
+}
+
+// NewFrontPanel returns a front panel attached to p
+func NewFrontPanel(p *PDP8) *FrontPanel {
+	fp := &FrontPanel{p: p, ma: p.pc, mb: p.ir}
+	p.AddObserver(fp)
+	return fp
+}
+
+// SetSwitches sets the switch register
+func (fp *FrontPanel) SetSwitches(sr uint) {
+	fp.p.SetSR(sr)
+}
+
+// SetFieldSwitches sets the extended address and data field switches
+// used by Load Address
+func (fp *FrontPanel) SetFieldSwitches(instField uint, dataField uint) {
+	fp.instField = instField & 0o7
+	fp.dataField = dataField & 0o7
+}
+
+// SetSingleStep sets the single step switch, when on the machine
+// stops after each memory cycle
+func (fp *FrontPanel) SetSingleStep(on bool) {
+	fp.singleStep = on
+}
+
+// SetSingleInstruction sets the single instruction switch, when on
+// the machine stops after each instruction
+func (fp *FrontPanel) SetSingleInstruction(on bool) {
+	fp.singleInstruction = on
+}
+
+// LoadAddress loads the PC from the switch register and the
+// instruction and data fields from the field switches
+func (fp *FrontPanel) LoadAddress() {
+	p := fp.p
+	p.pc = p.sr
+	p.ifr = fp.instField
+	p.ib = fp.instField
+	p.dfr = fp.dataField
+	fp.ma = p.pc
+}
+
+// Deposit stores the switch register at the PC in the instruction
+// field and then increments the PC
+func (fp *FrontPanel) Deposit() {
+	p := fp.p
+	fp.ma = p.pc
+	fp.mb = p.sr
+	if addr := p.ifr<<12 | p.pc; addr < uint(len(p.mem)) {
+		p.mem[addr] = p.sr
+	}
+	p.pc = mask(p.pc + 1)
+}
+
+// Examine shows the word at the PC in the instruction field in the
+// memory buffer and then increments the PC
+func (fp *FrontPanel) Examine() {
+	p := fp.p
+	fp.ma = p.pc
+	fp.mb = 0
+	if addr := p.ifr<<12 | p.pc; addr < uint(len(p.mem)) {
+		fp.mb = p.mem[addr]
+	}
+	p.pc = mask(p.pc + 1)
+}
+
+// Start clears AC, L and ION and sets the machine running from the PC
+func (fp *FrontPanel) Start() {
+	p := fp.p
+	p.lac = 0
+	p.ien = false
+	p.pendingIen = false
+	fp.running.Store(true)
+}
+
+// Continue sets the machine running from the PC
+func (fp *FrontPanel) Continue() {
+	fp.running.Store(true)
+}
+
+// Stop stops the machine at the end of the current instruction.
+// This can be called while another goroutine is calling Run.
+func (fp *FrontPanel) Stop() {
+	fp.running.Store(false)
+}
+
+// Run executes up to cycles instructions while the machine is
+// running.  The machine stops if a HLT is executed, the single
+// step or single instruction switch is on or an error occurs.
+// When single step is on, a major state is executed rather than
+// an instruction, see StepState.
+func (fp *FrontPanel) Run(cycles int) error {
+	var hlt bool
+	var err error
+	for ; cycles > 0 && fp.running.Load(); cycles-- {
+		if fp.singleStep {
+			hlt, err = fp.stepState()
+		} else {
+			// The rest of an instruction being stepped through
+			// has already been executed
+			fp.pending = fp.pending[:0]
+			hlt, _, err = fp.p.cycle()
+		}
+		if err != nil || hlt || fp.singleStep || fp.singleInstruction {
+			fp.running.Store(false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lights returns the state of the lights
+func (fp *FrontPanel) Lights() Lights {
+	if len(fp.pending) > 0 {
+		l := fp.last.lights
+		l.State = fp.pending[0].State
+		l.Run = fp.running.Load()
+		return l
+	}
+	l := fp.machineLights()
+	l.Run = fp.running.Load()
+	return l
+}
+
+// LastCycle returns the memory cycle last executed by StepState
+func (fp *FrontPanel) LastCycle() MemoryCycle {
+	return fp.last
+}
+
+// machineLights returns the lights for the machine between instructions
+func (fp *FrontPanel) machineLights() Lights {
+	p := fp.p
+	return Lights{
+		PC:    p.pc,
+		MA:    fp.ma,
+		MB:    fp.mb,
+		AC:    mask(p.lac),
+		L:     p.lac >> 12,
+		MQ:    p.mq,
+		IF:    p.ifr,
+		DF:    p.dfr,
+		IR:    p.ir >> 9,
+		State: StateFetch,
+		ION:   p.ien,
+	}
+}
+
+func (fp *FrontPanel) InstructionFetched(pc uint, ir uint) {
+	if fp.recording {
+		fp.recordFetch(pc, ir)
+		return
+	}
+	fp.ma, fp.mb = mask(pc), ir
+}
+
+func (fp *FrontPanel) MemoryRead(addr uint, value uint) {
+	if fp.recording {
+		fp.recordAccess(addr, value)
+		return
+	}
+	fp.ma, fp.mb = mask(addr), value
+}
+
+func (fp *FrontPanel) MemoryWrite(addr uint, oldValue uint, newValue uint) {
+	if fp.recording {
+		// Data breaks aren't memory cycles of the instruction
+		if !fp.p.inBreak {
+			fp.recordAccess(addr, newValue)
+		}
+		return
+	}
+	fp.ma, fp.mb = mask(addr), newValue
+}
@@ -0,0 +1,58 @@
+package pdp8
+
+import "testing"
+
+func TestFrontPanel(t *testing.T) {
+	p, err := New(WithMemorySize(8192))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := NewFrontPanel(p)
+
+	// Toggle in a program at 10200
+	fp.SetFieldSwitches(1, 0)
+	fp.SetSwitches(0o200)
+	fp.LoadAddress()
+	for _, v := range []uint{0o7001, 0o1205, 0o7402, 0o7402, 0o7402, 0o0003} {
+		fp.SetSwitches(v)
+		fp.Deposit()
+	}
+	if p.mem[0o10205] != 3 {
+		t.Fatalf("got mem[10205]: %04o, want: 0003", p.mem[0o10205])
+	}
+
+	// Examine what was deposited
+	fp.SetSwitches(0o201)
+	fp.LoadAddress()
+	fp.Examine()
+	l := fp.Lights()
+	if l.MA != 0o201 || l.MB != 0o1205 || l.PC != 0o202 {
+		t.Errorf("got MA: %04o, MB: %04o, PC: %04o, want MA: 0201, MB: 1205, PC: 0202",
+			l.MA, l.MB, l.PC)
+	}
+
+	// Single instruction
+	fp.SetSwitches(0o200)
+	fp.LoadAddress()
+	fp.SetSingleInstruction(true)
+	fp.Start()
+	if err := fp.Run(100); err != nil {
+		t.Fatal(err)
+	}
+	l = fp.Lights()
+	if l.AC != 1 || l.PC != 0o201 || l.Run {
+		t.Errorf("got AC: %04o, PC: %04o, Run: %t, want AC: 0001, PC: 0201, Run: false",
+			l.AC, l.PC, l.Run)
+	}
+
+	fp.SetSingleInstruction(false)
+	fp.Continue()
+	if err := fp.Run(100); err != nil {
+		t.Fatal(err)
+	}
+	l = fp.Lights()
+	want := Lights{PC: 0o203, MA: 0o202, MB: 0o7402, AC: 4, IF: 1, IR: 7}
+	if l != want {
+		t.Errorf("got: %+v, want: %+v", l, want)
+	}
+}
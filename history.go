@@ -0,0 +1,167 @@
+/*
+ * A history of the last instructions executed
+ *
+ * This records enough about each instruction that it can be undone
+ * with StepBack so that it is possible to walk backwards from a
+ * failure to see how execution got there.  The state of devices
+ * isn't recorded and therefore isn't undone.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoHistory is returned by StepBack when there is no history left
+var ErrNoHistory = errors.New("no history")
+
+// HistoryEntry describes an instruction that was executed
+type HistoryEntry struct {
+	PC     uint          // Address of the instruction, bits 12-14 are the field
+	IR     uint          // The instruction
+	EA     uint          // Effective address for memory reference instructions
+	Before State         // The registers before the instruction
+	After  State         // The registers after the instruction
+	Writes []MemoryWrite // Memory written, in order
+	// Whether an interrupt was taken after the instruction, in which
+	// case After is the state once the interrupt has been taken
+	InterruptTaken bool
+	regs           regs   // The registers needed to undo the instruction
+	cycles         uint64 // The cycles executed before the instruction
+}
+
+// MemoryWrite describes a change to memory
+type MemoryWrite struct {
+	Addr     uint // Address, bits 12-14 are the field
+	OldValue uint
+	NewValue uint
+}
+
+func (e HistoryEntry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%05o %04o EA: %05o L/AC: %o/%04o -> %o/%04o",
+		e.PC, e.IR, e.EA, e.Before.L, e.Before.AC, e.After.L, e.After.AC)
+	for _, w := range e.Writes {
+		fmt.Fprintf(&sb, " [%05o] %04o -> %04o", w.Addr, w.OldValue, w.NewValue)
+	}
+	if e.InterruptTaken {
+		sb.WriteString(" interrupt")
+	}
+	return sb.String()
+}
+
+// regs are the registers that an instruction can change
+type regs struct {
+	pc, ir, lac, mq, ifr, dfr, ib, sf uint
+	intInhibit, ien, pendingIen       bool
+}
+
+func (p *PDP8) saveRegs() regs {
+	return regs{
+		pc: p.pc, ir: p.ir, lac: p.lac, mq: p.mq,
+		ifr: p.ifr, dfr: p.dfr, ib: p.ib, sf: p.sf,
+		intInhibit: p.intInhibit, ien: p.ien, pendingIen: p.pendingIen,
+	}
+}
+
+func (p *PDP8) restoreRegs(r regs) {
+	p.pc, p.ir, p.lac, p.mq = r.pc, r.ir, r.lac, r.mq
+	p.ifr, p.dfr, p.ib, p.sf = r.ifr, r.dfr, r.ib, r.sf
+	p.intInhibit, p.ien, p.pendingIen = r.intInhibit, r.ien, r.pendingIen
+}
+
+// history is a ring buffer of the last instructions executed
+type history struct {
+	entries []HistoryEntry
+	next    int  // Index of the next entry to use
+	n       int  // Number of entries in use
+	current bool // Whether an instruction is being recorded
+}
+
+// SetHistorySize sets the number of instructions to keep in the
+// history, 0 turns the history off.  Any existing history is lost.
+func (p *PDP8) SetHistorySize(n int) {
+	if n <= 0 {
+		p.history = nil
+		return
+	}
+	p.history = &history{entries: make([]HistoryEntry, n)}
+}
+
+// History returns the instructions in the history, oldest first
+func (p *PDP8) History() []HistoryEntry {
+	h := p.history
+	if h == nil {
+		return nil
+	}
+	r := make([]HistoryEntry, 0, h.n)
+	for i := 0; i < h.n; i++ {
+		e := h.entries[(h.next-h.n+i+len(h.entries))%len(h.entries)]
+		e.Writes = append([]MemoryWrite(nil), e.Writes...)
+		r = append(r, e)
+	}
+	return r
+}
+
+// StepBack undoes the last instruction in the history.  Memory
+// and registers are restored but devices are not.
+func (p *PDP8) StepBack() error {
+	h := p.history
+	if h == nil || h.n == 0 {
+		return ErrNoHistory
+	}
+	h.next = (h.next - 1 + len(h.entries)) % len(h.entries)
+	h.n--
+	e := &h.entries[h.next]
+	for i := len(e.Writes) - 1; i >= 0; i-- {
+		p.mem[e.Writes[i].Addr] = e.Writes[i].OldValue
+	}
+	p.restoreRegs(e.regs)
+	p.cycles = e.cycles
+	return nil
+}
+
+// begin starts recording an instruction
+func (h *history) begin(p *PDP8) {
+	e := &h.entries[h.next]
+	e.regs = p.saveRegs()
+	e.cycles = p.cycles
+	e.Before = p.State()
+	e.Writes = e.Writes[:0]
+	e.InterruptTaken = false
+	h.current = true
+}
+
+// executed records the instruction once it has been executed
+func (h *history) executed(pc uint, ir uint, ea uint) {
+	e := &h.entries[h.next]
+	e.PC, e.IR, e.EA = pc, ir, ea
+}
+
+// write records a write to memory
+func (h *history) write(addr uint, oldValue uint, newValue uint) {
+	if !h.current {
+		return
+	}
+	e := &h.entries[h.next]
+	e.Writes = append(e.Writes, MemoryWrite{addr, oldValue, newValue})
+}
+
+// end finishes recording an instruction
+func (h *history) end(p *PDP8, interruptTaken bool) {
+	e := &h.entries[h.next]
+	e.After = p.State()
+	e.InterruptTaken = interruptTaken
+	h.current = false
+	h.next = (h.next + 1) % len(h.entries)
+	if h.n < len(h.entries) {
+		h.n++
+	}
+}
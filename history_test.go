@@ -0,0 +1,149 @@
+package pdp8
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o2210, // ISZ 210
+		0o202: 0o3211, // DCA 211
+		0o203: 0o7402, // HLT
+		0o210: 0o7776,
+	}
+
+	p, err := New(WithHistory(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	hlt, _, err := p.Run(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hlt {
+		t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
+	}
+
+	got := p.History()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want: 2", len(got))
+	}
+	if got[0].PC != 0o202 || got[0].IR != 0o3211 || got[0].EA != 0o211 ||
+		got[0].Before.AC != 1 || got[0].After.AC != 0 {
+		t.Errorf("got: %v, want: DCA 211 at 0202 with AC 0001 -> 0000", got[0])
+	}
+	wantWrites := []MemoryWrite{{Addr: 0o211, OldValue: 0, NewValue: 1}}
+	if !reflect.DeepEqual(got[0].Writes, wantWrites) {
+		t.Errorf("got writes: %v, want: %v", got[0].Writes, wantWrites)
+	}
+	if got[1].PC != 0o203 || got[1].IR != 0o7402 {
+		t.Errorf("got: %v, want: HLT at 0203", got[1])
+	}
+}
+
+func TestStepBack(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o2210, // ISZ 210
+		0o202: 0o3211, // DCA 211
+		0o203: 0o7402, // HLT
+		0o210: 0o7776,
+	}
+
+	p, err := New(WithHistory(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+	want := p.State()
+	wantMem := append([]uint(nil), p.mem...)
+
+	if _, _, err := p.Run(500); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := p.StepBack(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := p.State(); got != want {
+		t.Errorf("got state: %+v, want: %+v", got, want)
+	}
+	if !reflect.DeepEqual(p.mem, wantMem) {
+		t.Error("memory not restored")
+	}
+	if p.Cycles() != 0 {
+		t.Errorf("got cycles: %d, want: 0", p.Cycles())
+	}
+	if err := p.StepBack(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("got error: %v, want: %v", err, ErrNoHistory)
+	}
+}
+
+func TestStepBack_Step(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o2210, // ISZ 210
+		0o202: 0o3211, // DCA 211
+		0o203: 0o7402, // HLT
+		0o210: 0o7776,
+	}
+
+	p, err := New(WithHistory(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+	want := p.State()
+	wantMem := append([]uint(nil), p.mem...)
+
+	// Mix instructions run by Run and Step
+	if _, _, err := p.Run(1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := p.History()
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want: 3", len(got))
+	}
+	if got[2].PC != 0o202 || got[2].IR != 0o3211 || got[2].Before.AC != 1 ||
+		got[2].After.AC != 0 {
+		t.Errorf("got: %v, want: DCA 211 at 0202 with AC 0001 -> 0000", got[2])
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := p.StepBack(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := p.State(); got != want {
+		t.Errorf("got state: %+v, want: %+v", got, want)
+	}
+	if !reflect.DeepEqual(p.mem, wantMem) {
+		t.Error("memory not restored")
+	}
+	if p.Cycles() != 0 {
+		t.Errorf("got cycles: %d, want: 0", p.Cycles())
+	}
+	if err := p.StepBack(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("got error: %v, want: %v", err, ErrNoHistory)
+	}
+}
@@ -0,0 +1,43 @@
+/*
+ * Logging support
+ *
+ * Diagnostics are sent to a log/slog logger so that programs
+ * embedding the emulator can decide where, if anywhere, they go.
+ * By default nothing is logged.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// discardLogger is used when a logger hasn't been set
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler that discards every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// SetLogger sets the logger used for diagnostics, nil stops logging
+func (p *PDP8) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = discardLogger
+	}
+	p.logger = l
+}
+
+// octal returns an attribute with v formatted as a 4 digit octal number
+func octal(key string, v uint) slog.Attr {
+	return slog.String(key, fmt.Sprintf("%04o", v))
+}
@@ -0,0 +1,49 @@
+package pdp8
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_logging(t *testing.T) {
+	tape := []byte{
+		0o200, 0o200, // Leader
+		0o102, 0o000, // Origin 0200
+		0o012, 0o034, // 1234
+		0o000, 0o001, // 0001
+		0o103, 0o000, // Origin 0300
+		0o000, 0o002, // 0002
+		0o000, 0o051, // Checksum
+		0o200, 0o200, // Trailer
+	}
+	filename := filepath.Join(t.TempDir(), "log.bin")
+	if err := os.WriteFile(filename, tape, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	logOut := &bytes.Buffer{}
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetLogger(slog.New(slog.NewTextHandler(logOut,
+		&slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	if err := p.Load(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"msg=\"loaded block\" start=0200 end=0201",
+		"msg=\"loaded block\" start=0300 end=0300",
+		"msg=\"checksum OK\" checksum=0051",
+	} {
+		if !strings.Contains(logOut.String(), want) {
+			t.Errorf("log doesn't contain: %s, got: %s", want, logOut.String())
+		}
+	}
+}
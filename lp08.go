@@ -0,0 +1,276 @@
+/*
+ * An LP08 line printer, also used for the LE8 on the PDP-8/E
+ *
+ * The printer is device 66 and writes what it prints to an io.Writer.
+ * Characters are held in a line buffer and a line is printed when a
+ * line feed, form feed or carriage return is received.  A carriage
+ * return that isn't followed by a line feed or form feed causes the
+ * next line to be printed over the top of the last, which is output
+ * as a carriage return.  Characters past the last column are lost.
+ *
+ * When a page length is set, form feeds are turned into line feeds
+ * to move to the top of the next page, otherwise they are written
+ * out as form feeds.
+ *
+ * The printer's flag is set once it has had time to load a character
+ * or advance the paper.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The defaults for the printer
+const (
+	lpDefaultColumns        = 80
+	lpDefaultPageLength     = 66
+	lpDefaultLinesPerMinute = 300
+)
+
+// The number of cycles to load a character into the line buffer
+const lpCharCycles = instructionsPerSecond * 10 / 1000000
+
+// Control characters the printer acts on
+const (
+	lpLF = 0o12
+	lpFF = 0o14
+	lpCR = 0o15
+)
+
+type LP08 struct {
+	p *PDP8 // The machine attached to
+
+	flag      bool   // Printer is ready for a new character
+	busy      bool   // Printer is loading or printing a character
+	done      uint64 // The cycle the printer finishes
+	intEnable bool   // Whether the flag or being offline cause interrupts
+	offline   bool   // Whether the printer has been taken offline
+
+	line       []byte // The line buffer
+	col        int    // The column the next character will go in
+	overprint  bool   // A carriage return has printed the line buffer
+	pageLine   int    // The line on the page being printed
+	columns    int    // The number of columns on a line
+	pageLength int    // The number of lines on a page, 0 for none
+	lineCycles uint64 // The number of cycles to advance a line
+
+	out io.Writer // Where the printed output goes
+}
+
+// NewLP08 returns an 80 column, 300 lines per minute printer with
+// 66 lines per page, which writes to out
+func NewLP08(out io.Writer) *LP08 {
+	lp := &LP08{out: out, intEnable: true, pageLength: lpDefaultPageLength}
+	lp.SetColumns(lpDefaultColumns)
+	lp.SetLinesPerMinute(lpDefaultLinesPerMinute)
+	return lp
+}
+
+// SetColumns sets the number of columns on a line, normally 80 or 132
+func (lp *LP08) SetColumns(n int) {
+	if n > 0 {
+		lp.columns = n
+		lp.line = make([]byte, 0, n)
+		lp.col = 0
+	}
+}
+
+// SetPageLength sets the number of lines on a page, 0 writes form
+// feeds out rather than turning them into line feeds
+func (lp *LP08) SetPageLength(n int) {
+	if n >= 0 {
+		lp.pageLength = n
+		lp.pageLine = 0
+	}
+}
+
+// SetLinesPerMinute sets how quickly the printer prints lines
+func (lp *LP08) SetLinesPerMinute(n int) {
+	if n > 0 {
+		lp.lineCycles = uint64(instructionsPerSecond * 60 / n)
+	}
+}
+
+// SetOnline puts the printer online or takes it offline.  The error
+// flag is set while the printer is offline or has nowhere to print.
+func (lp *LP08) SetOnline(on bool) {
+	lp.offline = !on
+}
+
+func (lp *LP08) Attach(p *PDP8) {
+	lp.p = p
+}
+
+// Close prints anything left in the line buffer but doesn't close
+// the writer passed to it
+func (lp *LP08) Close() error {
+	if len(lp.line) == 0 || lp.out == nil {
+		return nil
+	}
+	return lp.write(nil)
+}
+
+func (lp *LP08) DeviceNumbers() []int {
+	return []int{0o66}
+}
+
+func (lp *LP08) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "LP08",
+		Description: "Line printer",
+	}
+}
+
+// Reset clears the flag, the line buffer and stops the printer.  The
+// page position is left alone.
+func (lp *LP08) Reset() {
+	lp.CAF()
+	lp.busy = false
+	lp.line = lp.line[:0]
+	lp.col = 0
+	lp.overprint = false
+}
+
+// CAF clears the flag and enables interrupts
+func (lp *LP08) CAF() {
+	lp.flag = false
+	lp.intEnable = true
+}
+
+// Interrupt returns if the flag or error flag is set
+func (lp *LP08) Interrupt() (bool, error) {
+	lp.update()
+	return lp.intEnable && (lp.flag || lp.isError()), nil
+}
+
+// update sets the flag if the printer has had long enough
+func (lp *LP08) update() {
+	if lp.busy && attachedCycles(lp.p) >= lp.done {
+		lp.busy = false
+		lp.flag = true
+	}
+}
+
+// isError returns whether the printer can't print
+func (lp *LP08) isError() bool {
+	return lp.offline || lp.out == nil
+}
+
+// print puts a 7-bit character into the line buffer or acts on it
+// if it is a control character and starts the printer
+func (lp *LP08) print(c byte) error {
+	var err error
+	cycles := uint64(lpCharCycles)
+
+	switch c {
+	case lpLF:
+		err = lp.write([]byte{'\n'})
+		lp.advance(1)
+		cycles = lp.lineCycles
+	case lpFF:
+		if lp.pageLength == 0 {
+			err = lp.write([]byte{'\f'})
+			cycles = lp.lineCycles
+		} else {
+			n := lp.pageLength - lp.pageLine
+			eject := make([]byte, n)
+			for i := range eject {
+				eject[i] = '\n'
+			}
+			err = lp.write(eject)
+			lp.advance(n)
+			cycles = uint64(n) * lp.lineCycles
+		}
+	case lpCR:
+		lp.col = 0
+		if len(lp.line) > 0 {
+			lp.overprint = true
+		}
+	default:
+		if c >= ' ' && c < 0o177 {
+			if lp.overprint {
+				err = lp.write([]byte{'\r'})
+				cycles = lp.lineCycles
+			}
+			if lp.col < lp.columns {
+				lp.put(c)
+			}
+		}
+	}
+	lp.busy = true
+	lp.done = attachedCycles(lp.p) + cycles
+	return err
+}
+
+// put puts c in the line buffer at the current column
+func (lp *LP08) put(c byte) {
+	for len(lp.line) < lp.col {
+		lp.line = append(lp.line, ' ')
+	}
+	if lp.col < len(lp.line) {
+		lp.line[lp.col] = c
+	} else {
+		lp.line = append(lp.line, c)
+	}
+	lp.col++
+}
+
+// write writes the line buffer followed by end and empties the buffer
+func (lp *LP08) write(end []byte) error {
+	buf := append(lp.line, end...)
+	lp.line = lp.line[:0]
+	lp.col = 0
+	lp.overprint = false
+	n, err := lp.out.Write(buf)
+	if err != nil {
+		return fmt.Errorf("LP08: %w", err)
+	}
+	if n != len(buf) {
+		return errors.New("LP08: write failed")
+	}
+	return nil
+}
+
+// advance moves the paper on n lines
+func (lp *LP08) advance(n int) {
+	if lp.pageLength > 0 {
+		lp.pageLine = (lp.pageLine + n) % lp.pageLength
+	}
+}
+
+// IOT returns PC, LAC, error
+func (lp *LP08) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	var err error
+
+	lp.update()
+	switch ir & 0o7 {
+	case 0o1: // PSKF - Skip if flag set
+		if lp.flag {
+			pc = mask(pc + 1)
+		}
+	case 0o3: // PSKE - Skip if error
+		if lp.isError() {
+			pc = mask(pc + 1)
+		}
+	case 0o5: // PSIE - Set interrupt enable
+		lp.intEnable = true
+	case 0o7: // PCIE - Clear interrupt enable
+		lp.intEnable = false
+	default:
+		if (ir & 0o2) == 0o2 { // PCLF - Clear flag
+			lp.flag = false
+		}
+		if (ir&0o4) == 0o4 && !lp.isError() { // PSTB - Print character
+			err = lp.print(byte(lac & 0o177))
+		}
+	}
+	return pc, lac, err
+}
@@ -0,0 +1,160 @@
+package pdp8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// lpPrint prints each character of s through the IOT, without waiting
+// for the printer
+func lpPrint(t *testing.T, lp *LP08, s string) {
+	t.Helper()
+	for _, c := range []byte(s) {
+		if _, _, err := lp.IOT(0o6666, 0o200, uint(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLP08_print(t *testing.T) {
+	cases := []struct {
+		name       string
+		columns    int
+		pageLength int
+		in         string
+		want       string
+	}{
+		{name: "lines",
+			in:   "HELLO\r\nWORLD\r\n",
+			want: "HELLO\nWORLD\n"},
+		{name: "overprint",
+			in:   "ABC\r___\r\n",
+			want: "ABC\r___\n"},
+		{name: "overprint part of line",
+			in:   "ABCDEF\r  X\r\n",
+			want: "ABCDEF\r  X\n"},
+		{name: "columns", columns: 4,
+			in:   "ABCDEF\r\n",
+			want: "ABCD\n"},
+		{name: "form feed",
+			in:   "A\r\nB\r\n\f",
+			want: "A\nB\n\f"},
+		{name: "page length", pageLength: 4,
+			in:   "A\r\n\fB\f\f",
+			want: "A\n\n\n\nB\n\n\n\n\n\n\n\n"},
+		{name: "unprintable ignored",
+			in:   "A\x07\tB\r\n",
+			want: "AB\n"},
+		{name: "close prints line buffer",
+			in:   "END",
+			want: "END"},
+	}
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		lp := NewLP08(out)
+		lp.SetPageLength(c.pageLength)
+		if c.columns != 0 {
+			lp.SetColumns(c.columns)
+		}
+		lpPrint(t, lp, c.in)
+		if err := lp.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != c.want {
+			t.Errorf("%s - got: %q, want: %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestLP08_timing(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6666, // PCLF PSTB
+		0o201: 0o6661, // PSKF
+		0o202: 0o5201, // JMP 201
+		0o203: 0o7402, // HLT
+	}
+	cases := []struct {
+		ac   uint
+		want uint64
+	}{
+		{ac: 'A', want: lpCharCycles},
+		{ac: lpLF, want: instructionsPerSecond * 60 / 300},
+	}
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		lp := NewLP08(out)
+		p, err := New(WithDevice(lp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for addr, v := range testRoutine {
+			p.mem[addr] = v
+		}
+		p.lac = c.ac
+		if _, err := p.RunUntil(Halted(), CycleBudget(100000)); err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Cycles(); got < c.want || got > c.want+5 {
+			t.Errorf("%03o - got cycles: %d, want: about %d", c.ac, got, c.want)
+		}
+	}
+}
+
+func TestLP08_error(t *testing.T) {
+	cases := []struct {
+		name    string
+		out     *strings.Builder
+		offline bool
+		want    bool
+	}{
+		{name: "online", out: &strings.Builder{}, want: false},
+		{name: "offline", out: &strings.Builder{}, offline: true, want: true},
+		{name: "no output", want: true},
+	}
+	for _, c := range cases {
+		var lp *LP08
+		if c.out == nil {
+			lp = NewLP08(nil)
+		} else {
+			lp = NewLP08(c.out)
+		}
+		lp.SetOnline(!c.offline)
+		pc, _, err := lp.IOT(0o6663, 0o200, 0) // PSKE
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pc == 0o201; got != c.want {
+			t.Errorf("%s - got skip: %t, want: %t", c.name, got, c.want)
+		}
+		if got, _ := lp.Interrupt(); got != c.want {
+			t.Errorf("%s - got interrupt: %t, want: %t", c.name, got, c.want)
+		}
+		lpPrint(t, lp, "A\r\n")
+		if c.out != nil && c.offline && c.out.Len() != 0 {
+			t.Errorf("%s - printed while offline", c.name)
+		}
+	}
+}
+
+func TestLP08_interrupt(t *testing.T) {
+	lp := NewLP08(&bytes.Buffer{})
+	p, err := New(WithDevice(lp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lpPrint(t, lp, "A")
+	p.cycles += lpCharCycles
+	if got, _ := lp.Interrupt(); !got {
+		t.Error("no interrupt after printing")
+	}
+	lp.IOT(0o6667, 0o200, 0) // PCIE
+	if got, _ := lp.Interrupt(); got {
+		t.Error("interrupt after PCIE")
+	}
+	lp.IOT(0o6665, 0o200, 0) // PSIE
+	lp.IOT(0o6662, 0o200, 0) // PCLF
+	if got, _ := lp.Interrupt(); got {
+		t.Error("interrupt after PCLF")
+	}
+}
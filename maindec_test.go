@@ -17,18 +17,47 @@ import (
 func setupMaindecTest(t *testing.T, filename string) (*PDP8, *TTY, func()) {
 	rw := newDummyReadWriter()
 	tty := NewTTY(rw, rw)
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 
 	loadBINTape(t, p, tty, filepath.Join("fixtures", filename))
+
+	// Show how execution got to where a test failed
+	p.SetHistorySize(20)
+	t.Cleanup(func() {
+		if t.Failed() {
+			logHistory(t, p)
+		}
+	})
 	teardownMaindecTest := func() {
 		tty.Close()
 	}
 	return p, tty, teardownMaindecTest
 }
 
+// maindecCycleBudget is the most instructions a MAINDEC test is run
+// for before it is taken to be stuck
+const maindecCycleBudget = 1000000000
+
+// runMaindec runs p until cond is met or a HLT is executed.  The test
+// fails if neither happens within maindecCycleBudget instructions,
+// rather than running until go test times out.
+func runMaindec(t *testing.T, p *PDP8, cond Condition) Stop {
+	t.Helper()
+	budget := CycleBudget(maindecCycleBudget)
+	stop, err := p.RunUntil(cond, budget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition == budget {
+		t.Fatalf("%s not met within %d instructions - PC: %04o",
+			cond, maindecCycleBudget, p.pc)
+	}
+	return stop
+}
+
 // MAINDEC-08-D01A
 // Instruction test part 2A
 func TestRun_maindec_08_d01a(t *testing.T) {
@@ -38,8 +67,8 @@ func TestRun_maindec_08_d01a(t *testing.T) {
 	// ttyOut is so that we can check output
 	ttyOut := bytes.NewBuffer(make([]byte, 0, 5000))
 	tty := NewTTY(rw, ttyOut)
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -73,14 +102,16 @@ func TestRun_maindec_08_d01a(t *testing.T) {
 		127, 127, 127, 127, 127, 127, 127,
 		13, 10, 50, 65,
 	}
-	for !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
-		hlt, _, err = p.Run(5000)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if hlt {
-			t.Errorf("Test failed - HLT PC: %04o", p.pc-1)
+	// An unexpected HLT is reported and then the test carries on
+	for {
+		stop := runMaindec(t, p, OutputContains(ttyOut, ttyOutWant))
+		if !stop.Halted {
+			break
 		}
+		t.Errorf("Test failed - HLT PC: %04o", p.pc-1)
+	}
+	if !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
+		t.Errorf("got output: %v, want: %v", ttyOut.Bytes(), ttyOutWant)
 	}
 	// Test ends successfully
 }
@@ -127,8 +158,8 @@ func TestRun_maindec_08_d03a(t *testing.T) {
 	// ttyOut is so that we can check output
 	ttyOut := bytes.NewBuffer(make([]byte, 0, 5000))
 	tty := NewTTY(rw, ttyOut)
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -150,26 +181,14 @@ func TestRun_maindec_08_d03a(t *testing.T) {
 	p.sr = 0o7777
 
 	// Run Part 2
-	hlt := false
-	for !hlt {
-		hlt, _, err = p.Run(5000)
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
+	runMaindec(t, p, Halted())
 
 	// Run diagnostic program
 	p.pc = 0o600
 	p.sr = 0o0000
-	hlt = false
-	for !hlt {
-		hlt, _, err = p.Run(5000)
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
+	stop := runMaindec(t, p, Halted())
 
-	if !hlt {
+	if !stop.Halted {
 		t.Fatalf("Failed to execute HLT at PC: %04o", p.pc-1)
 	}
 
@@ -183,14 +202,12 @@ func TestRun_maindec_08_d03a(t *testing.T) {
 	// Bytes comparison represents:
 	//  CR, NL, '0', '3', CR, NL, '0', '3'
 	ttyOutWant := []byte{13, 10, 48, 51, 13, 10, 48, 51}
-	for !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
-		hlt, _, err := p.Run(5000)
-		if err != nil {
-			t.Fatal(err)
-		}
-		if hlt {
-			t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
-		}
+	stop = runMaindec(t, p, OutputContains(ttyOut, ttyOutWant))
+	if stop.Halted {
+		t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
+	}
+	if !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
+		t.Fatalf("got output: %v, want: %v", ttyOut.Bytes(), ttyOutWant)
 	}
 	// Test ends successfully
 }
@@ -203,8 +220,8 @@ func TestRun_maindec_08_d04b(t *testing.T) {
 	// ttyOut is so that we can check output
 	ttyOut := bytes.NewBuffer(make([]byte, 0, 5000))
 	tty := NewTTY(rw, ttyOut)
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -220,15 +237,12 @@ func TestRun_maindec_08_d04b(t *testing.T) {
 	// Bytes comparison represents:
 	//  0, CR, NL, '0', '4', CR, NL, '0', '3'
 	ttyOutWant := []byte{0, 13, 10, 48, 52, 13, 10, 48, 52}
-	for !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
-		hlt, _, err := p.Run(5000000)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		if hlt {
-			t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
-		}
+	stop := runMaindec(t, p, OutputContains(ttyOut, ttyOutWant))
+	if stop.Halted {
+		t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
+	}
+	if !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
+		t.Fatalf("got output: %v, want: %v", ttyOut.Bytes(), ttyOutWant)
 	}
 
 	// Test ends successfully
@@ -243,8 +257,8 @@ func TestRun_maindec_08_d05b(t *testing.T) {
 	// ttyOut is so that we can check output
 	ttyOut := bytes.NewBuffer(make([]byte, 0, 5000))
 	tty := NewTTY(rw, ttyOut)
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 	loadBINTape(t, p, tty, filepath.Join("fixtures", "maindec-08-d05b-pb.bin"))
@@ -259,16 +273,12 @@ func TestRun_maindec_08_d05b(t *testing.T) {
 	// Bytes comparison represents:
 	//  DEL, CR, NL, '0', '5', CR, NL, '0', '5'
 	ttyOutWant := []byte{127, 13, 10, 48, 53, 13, 10, 48, 53}
-	for !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
-
-		hlt, _, err := p.Run(5000000)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		if hlt {
-			t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
-		}
+	stop := runMaindec(t, p, OutputContains(ttyOut, ttyOutWant))
+	if stop.Halted {
+		t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
+	}
+	if !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
+		t.Fatalf("got output: %v, want: %v", ttyOut.Bytes(), ttyOutWant)
 	}
 
 	// Test ends successfully
@@ -281,8 +291,8 @@ func TestRun_maindec_08_d07b(t *testing.T) {
 	// ttyOut is so that we can check output
 	ttyOut := bytes.NewBuffer(make([]byte, 0, 5000))
 	tty := NewTTY(rw, ttyOut)
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 	loadBINTape(t, p, tty, filepath.Join("fixtures", "maindec-08-d07b-pb.bin"))
@@ -298,16 +308,12 @@ func TestRun_maindec_08_d07b(t *testing.T) {
 			48, 55, 13, 10, 10, 127,
 			48, 55, 13, 10, 10, 127,
 		}
-		for !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
-
-			hlt, _, err := p.Run(500)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			if hlt {
-				t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
-			}
+		stop := runMaindec(t, p, OutputContains(ttyOut, ttyOutWant))
+		if stop.Halted {
+			t.Fatalf("Test failed - HLT PC: %04o", p.pc-1)
+		}
+		if !bytes.Equal(ttyOut.Bytes(), ttyOutWant) {
+			t.Fatalf("got output: %v, want: %v", ttyOut.Bytes(), ttyOutWant)
 		}
 	}
 
@@ -468,15 +474,9 @@ func TestRun_maindec_08_d2pe_PRG0(t *testing.T) {
 		p.sr = 0o6000 + routine
 
 		// Run routine
-		hlt = false
-		for !hlt {
-			hlt, _, err = p.Run(5000)
-			if err != nil {
-				t.Fatal(err)
-			}
-		}
+		stop := runMaindec(t, p, Halted())
 
-		if !hlt {
+		if !stop.Halted {
 			t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
 		}
 
@@ -554,15 +554,9 @@ func TestRun_maindec_08_d2pe_PRG1(t *testing.T) {
 	p.sr = 0
 
 	// Run routine
-	hlt = false
-	for !hlt {
-		hlt, _, err = p.Run(5000)
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
+	stop := runMaindec(t, p, Halted())
 
-	if !hlt {
+	if !stop.Halted {
 		t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
 	}
 
@@ -615,15 +609,9 @@ func TestRun_maindec_08_d2pe_PRG2(t *testing.T) {
 	p.sr = 0
 
 	// Run routine
-	hlt = false
-	for !hlt {
-		hlt, _, err = p.Run(5000)
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
+	stop := runMaindec(t, p, Halted())
 
-	if !hlt {
+	if !stop.Halted {
 		t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
 	}
 
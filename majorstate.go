@@ -0,0 +1,246 @@
+/*
+ * Major states and timing pulses
+ *
+ * The PDP-8/I executes each instruction as a series of memory cycles,
+ * each in one of the major states: Fetch, Defer, Execute or Break.
+ * Each memory cycle is divided by the timing pulses TP1-TP4.  The
+ * emulator executes whole instructions, so to show the major states
+ * the memory cycles of an instruction are recorded as it is executed
+ * and then stepped through one at a time.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+// MajorState is the major state of the processor
+type MajorState int
+
+const (
+	StateFetch   MajorState = iota // Fetching an instruction
+	StateDefer                     // Fetching an indirect address
+	StateExecute                   // Executing an instruction
+	// Transferring data for a device.  Data breaks are made between
+	// instructions and aren't recorded so this state isn't entered.
+	StateBreak
+)
+
+func (s MajorState) String() string {
+	switch s {
+	case StateFetch:
+		return "fetch"
+	case StateDefer:
+		return "defer"
+	case StateExecute:
+		return "execute"
+	case StateBreak:
+		return "break"
+	}
+	return "unknown"
+}
+
+// MemoryCycle describes a memory cycle of an instruction
+type MemoryCycle struct {
+	State  MajorState
+	MA     uint      // Memory address
+	MB     uint      // Memory buffer
+	Pulses [4]string // What happens at TP1-TP4
+
+	lights Lights // The lights at the end of the cycle
+	ir     uint
+}
+
+// The timing pulses for each memory cycle of the major states
+var (
+	fetchPulses = [4]string{
+		"MA <- PC, read memory",
+		"MB <- memory, IR <- MB bits 0-2, PC <- PC+1",
+		"Write MB back to memory",
+		"Set next major state",
+	}
+	oprPulses = [4]string{
+		fetchPulses[0],
+		fetchPulses[1],
+		fetchPulses[2],
+		"Operate microinstructions, next state is Fetch",
+	}
+	iotPulses = [4]string{
+		fetchPulses[0],
+		fetchPulses[1],
+		"Write MB back to memory, IOP pulses to device",
+		"Next state is Fetch",
+	}
+	deferPulses = [4]string{
+		"MA <- address from instruction, read memory",
+		"MB <- memory, MB <- MB+1 if autoindex",
+		"Write MB back to memory",
+		"Set next major state",
+	}
+	jmpDeferPulses = [4]string{
+		deferPulses[0],
+		deferPulses[1],
+		deferPulses[2],
+		"PC <- MB, next state is Fetch",
+	}
+	executePulses = [5][4]string{
+		{ // AND
+			"MA <- operand address, read memory",
+			"MB <- memory",
+			"Write MB back to memory",
+			"AC <- AC AND MB, next state is Fetch",
+		},
+		{ // TAD
+			"MA <- operand address, read memory",
+			"MB <- memory",
+			"Write MB back to memory",
+			"AC <- AC + MB, next state is Fetch",
+		},
+		{ // ISZ
+			"MA <- operand address, read memory",
+			"MB <- memory + 1",
+			"Write MB to memory",
+			"PC <- PC+1 if MB = 0, next state is Fetch",
+		},
+		{ // DCA
+			"MA <- operand address",
+			"MB <- AC",
+			"Write MB to memory",
+			"AC <- 0, next state is Fetch",
+		},
+		{ // JMS
+			"MA <- subroutine address",
+			"MB <- PC",
+			"Write MB to memory",
+			"PC <- MA+1, next state is Fetch",
+		},
+	}
+	interruptPulses = [4]string{
+		"MA <- 0",
+		"MB <- PC",
+		"Write MB to memory",
+		"PC <- 1, ION <- 0, next state is Fetch",
+	}
+)
+
+// StepState executes a major state.  An instruction is executed when
+// its first major state is, the remaining states are then shown from
+// a record of the memory cycles it used.  The machine is stopped.
+func (fp *FrontPanel) StepState() error {
+	fp.running.Store(false)
+	_, err := fp.stepState()
+	return err
+}
+
+// stepState executes a major state and returns whether a HLT was
+// executed
+func (fp *FrontPanel) stepState() (bool, error) {
+	var hlt bool
+	var err error
+	if len(fp.pending) == 0 {
+		hlt, err = fp.recordInstruction()
+		if len(fp.pending) == 0 {
+			return hlt, err
+		}
+	}
+	c := fp.pending[0]
+	fp.pending = fp.pending[1:]
+	fp.last = c
+	fp.ma, fp.mb = c.MA, c.MB
+	return hlt, err
+}
+
+// recordInstruction executes an instruction recording its memory cycles
+func (fp *FrontPanel) recordInstruction() (bool, error) {
+	p := fp.p
+	before := fp.machineLights()
+	fp.recording = true
+	fp.pending = fp.pending[:0]
+	hlt, interrupt, err := p.cycle()
+	fp.recording = false
+	if n := len(fp.pending); interrupt && n > 0 {
+		// The last memory cycle stored the PC in location 0
+		fp.pending[n-1].Pulses = interruptPulses
+	}
+
+	// The registers are only known before and after the instruction
+	after := fp.machineLights()
+	for i := range fp.pending {
+		c := &fp.pending[i]
+		if c.State == StateExecute {
+			c.lights = after
+		} else {
+			c.lights = before
+			c.lights.PC = mask(before.PC + 1)
+			c.lights.IR = c.ir >> 9
+		}
+		c.lights.MA, c.lights.MB = c.MA, c.MB
+	}
+	return hlt, err
+}
+
+// recordCycle records a memory access as part of a memory cycle
+func (fp *FrontPanel) recordCycle(state MajorState, addr uint, value uint) {
+	addr = mask(addr)
+	// Accesses to the same address, such as ISZ reading and then
+	// writing, are part of the same memory cycle
+	if n := len(fp.pending); n > 0 {
+		last := &fp.pending[n-1]
+		if last.MA == addr && last.State == state && state != StateFetch {
+			last.MB = value
+			return
+		}
+	}
+	c := MemoryCycle{State: state, MA: addr, MB: value, ir: fp.ir}
+	opCode := fp.ir >> 9
+	switch {
+	case state == StateDefer && opCode == 5:
+		c.Pulses = jmpDeferPulses
+	case state == StateDefer:
+		c.Pulses = deferPulses
+	case opCode <= 4:
+		c.Pulses = executePulses[opCode]
+	}
+	fp.pending = append(fp.pending, c)
+}
+
+func (fp *FrontPanel) recordFetch(pc uint, ir uint) {
+	fp.ir = ir
+	c := MemoryCycle{State: StateFetch, MA: mask(pc), MB: ir, ir: ir}
+	opCode := ir >> 9
+	switch {
+	case opCode == 6:
+		c.Pulses = iotPulses
+	case opCode == 7:
+		c.Pulses = oprPulses
+	default:
+		c.Pulses = fetchPulses
+	}
+	fp.pending = append(fp.pending, c)
+
+	fp.deferAddr = noEntry
+	if opCode <= 5 && (ir&0o400) == 0o400 {
+		fp.deferAddr = ir & 0o177
+		if (ir & 0o200) == 0o200 {
+			fp.deferAddr |= pc & 0o7600
+		}
+	}
+	// A JMP has no execute state
+	if opCode == 5 && fp.deferAddr == noEntry {
+		fp.pending[len(fp.pending)-1].Pulses[3] = "PC <- MA, next state is Fetch"
+	}
+}
+
+func (fp *FrontPanel) recordAccess(addr uint, value uint) {
+	state := StateExecute
+	if mask(addr) == fp.deferAddr {
+		state = StateDefer
+		fp.deferAddr = noEntry
+	} else if n := len(fp.pending); n > 0 &&
+		fp.pending[n-1].State == StateDefer && fp.pending[n-1].MA == mask(addr) {
+		// An autoindex write and then read of the same address
+		state = StateDefer
+	}
+	fp.recordCycle(state, addr, value)
+}
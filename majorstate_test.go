@@ -0,0 +1,120 @@
+package pdp8
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrontPanel_StepState(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o1410, // TAD I 10
+		0o201: 0o3211, // DCA 211
+		0o202: 0o7402, // HLT
+		0o010: 0o0211,
+		0o212: 0o0005,
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+	fp := NewFrontPanel(p)
+
+	type light struct {
+		state     MajorState
+		ma, mb    uint
+		ac, pc    uint
+		nextState MajorState
+	}
+	want := []light{
+		{StateFetch, 0o200, 0o1410, 0, 0o201, StateDefer},
+		{StateDefer, 0o010, 0o0212, 0, 0o201, StateExecute},
+		{StateExecute, 0o212, 0o0005, 5, 0o201, StateFetch},
+		{StateFetch, 0o201, 0o3211, 5, 0o202, StateExecute},
+		{StateExecute, 0o211, 0o0005, 0, 0o202, StateFetch},
+		{StateFetch, 0o202, 0o7402, 0, 0o203, StateFetch},
+	}
+	var got []light
+	for range want {
+		if err := fp.StepState(); err != nil {
+			t.Fatal(err)
+		}
+		c := fp.LastCycle()
+		l := fp.Lights()
+		if l.MA != c.MA || l.MB != c.MB {
+			t.Errorf("lights MA: %04o, MB: %04o don't match cycle MA: %04o, MB: %04o",
+				l.MA, l.MB, c.MA, c.MB)
+		}
+		got = append(got, light{c.State, c.MA, c.MB, l.AC, l.PC, l.State})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if c := fp.LastCycle(); c.Pulses != oprPulses {
+		t.Errorf("got pulses: %q, want: %q", c.Pulses, oprPulses)
+	}
+}
+
+func TestFrontPanel_Run_singleStep(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o2210, // ISZ 210
+		0o201: 0o7402, // HLT
+		0o210: 0o0007,
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+	fp := NewFrontPanel(p)
+	fp.SetSingleStep(true)
+
+	for _, want := range []MajorState{StateFetch, StateExecute} {
+		fp.Continue()
+		if err := fp.Run(100); err != nil {
+			t.Fatal(err)
+		}
+		if c := fp.LastCycle(); c.State != want || fp.Lights().Run {
+			t.Errorf("got state: %s, run: %t, want state: %s, run: false",
+				c.State, fp.Lights().Run, want)
+		}
+	}
+	l := fp.Lights()
+	if l.MA != 0o210 || l.MB != 0o10 || l.State != StateFetch {
+		t.Errorf("got MA: %04o, MB: %04o, state: %s, want MA: 0210, MB: 0010, state: fetch",
+			l.MA, l.MB, l.State)
+	}
+}
+
+func TestFrontPanel_StepState_dataBreak(t *testing.T) {
+	dt := newDTImage(t, 129)
+	defer dt.Close()
+	dt.units[0].pos = 3
+	p := newDeviceMachine(t, dt, dtRoutine(0o10, 0o220),
+		map[uint]uint{dtWCAddr: 0o7600, dtWCAddr + 1: 0o777})
+	p.pc = 0o200
+	fp := NewFrontPanel(p)
+
+	// The DTSF loop is only fetches while the block is read
+	for i := 0; i < 500000 && p.pc != 0o206; i++ {
+		if err := fp.StepState(); err != nil {
+			t.Fatal(err)
+		}
+		if c := fp.LastCycle(); (c.ir == 0o6771 || c.ir == 0o5204) &&
+			(c.State != StateFetch || len(fp.pending) != 0) {
+			t.Fatalf("got state: %s, cycles left: %d, for IR: %04o",
+				c.State, len(fp.pending), c.ir)
+		}
+	}
+	if p.pc != 0o206 || p.mem[0o11000] != 3<<7 {
+		t.Errorf("got PC: %04o, mem[11000]: %04o", p.pc, p.mem[0o11000])
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * The models of PDP-8 that can be emulated
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import "fmt"
+
+// Model is the model of PDP-8 being emulated.  Most instructions are
+// the same on every model, so the differences are kept to a minimum.
+type Model int
+
+const (
+	ModelPDP8  Model = iota // The original PDP-8, the 'Straight 8'
+	ModelPDP8S              // PDP-8/S
+	ModelPDP8I              // PDP-8/I
+	ModelPDP8L              // PDP-8/L
+	ModelPDP8E              // PDP-8/E with the MQ and BSW instructions
+)
+
+func (m Model) String() string {
+	switch m {
+	case ModelPDP8:
+		return "PDP-8"
+	case ModelPDP8S:
+		return "PDP-8/S"
+	case ModelPDP8I:
+		return "PDP-8/I"
+	case ModelPDP8L:
+		return "PDP-8/L"
+	case ModelPDP8E:
+		return "PDP-8/E"
+	}
+	return fmt.Sprintf("Model(%d)", int(m))
+}
+
+// Model returns the model being emulated
+func (p *PDP8) Model() Model {
+	return p.model
+}
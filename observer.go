@@ -0,0 +1,122 @@
+/*
+ * An observer interface
+ *
+ * This allows tracers, profilers, GUIs, etc to watch what the
+ * emulator is doing without having to alter it.  When no observers
+ * are attached the only cost is a nil check.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import "reflect"
+
+// Observer is notified of activity within the emulator.  Callbacks
+// are made from within Run/Step and therefore shouldn't alter the
+// machine.
+type Observer interface {
+	// InstructionFetched is called once an instruction has been
+	// fetched from pc and before any indirect address is resolved
+	InstructionFetched(pc uint, ir uint)
+	// InstructionExecuted is called once the instruction fetched from
+	// pc has been executed.  ea is the effective address used by
+	// memory reference instructions, otherwise it is 0.
+	InstructionExecuted(pc uint, ir uint, ea uint)
+	// MemoryRead is called when an instruction reads an operand or
+	// an indirect address from memory
+	MemoryRead(addr uint, value uint)
+	// MemoryWrite is called before an instruction or a data break
+	// changes memory
+	MemoryWrite(addr uint, oldValue uint, newValue uint)
+	// IOT is called before an IOT instruction at pc is passed to
+	// the devices
+	IOT(pc uint, ir uint)
+	// InterruptTaken is called when an interrupt is taken, pc is the
+	// return address which has been stored in location 0
+	InterruptTaken(pc uint)
+	// Halted is called when a HLT instruction at pc is executed
+	Halted(pc uint)
+}
+
+// NopObserver implements Observer with methods that do nothing so that
+// it can be embedded by observers only interested in some callbacks
+type NopObserver struct{}
+
+func (NopObserver) InstructionFetched(pc uint, ir uint)                 {}
+func (NopObserver) InstructionExecuted(pc uint, ir uint, ea uint)       {}
+func (NopObserver) MemoryRead(addr uint, value uint)                    {}
+func (NopObserver) MemoryWrite(addr uint, oldValue uint, newValue uint) {}
+func (NopObserver) IOT(pc uint, ir uint)                                {}
+func (NopObserver) InterruptTaken(pc uint)                              {}
+func (NopObserver) Halted(pc uint)                                      {}
+
+// AddObserver attaches an observer to the machine.  Observers should
+// be pointers so that they can be removed with RemoveObserver.
+func (p *PDP8) AddObserver(o Observer) {
+	p.observers = append(p.observers, o)
+}
+
+// RemoveObserver detaches an observer from the machine.  Observers are
+// found by comparing them, so an observer that isn't comparable, such
+// as a struct containing a slice rather than a pointer to it, can't
+// be removed.
+func (p *PDP8) RemoveObserver(o Observer) {
+	if o == nil || !reflect.TypeOf(o).Comparable() {
+		return
+	}
+	for i, ob := range p.observers {
+		if ob == o {
+			p.observers = append(p.observers[:i:i], p.observers[i+1:]...)
+			break
+		}
+	}
+	// Keep observers nil when empty so that checks stay cheap
+	if len(p.observers) == 0 {
+		p.observers = nil
+	}
+}
+
+func (p *PDP8) notifyInstructionFetched(pc uint, ir uint) {
+	for _, o := range p.observers {
+		o.InstructionFetched(pc, ir)
+	}
+}
+
+func (p *PDP8) notifyInstructionExecuted(pc uint, ir uint, ea uint) {
+	for _, o := range p.observers {
+		o.InstructionExecuted(pc, ir, ea)
+	}
+}
+
+func (p *PDP8) notifyMemoryRead(addr uint, value uint) {
+	for _, o := range p.observers {
+		o.MemoryRead(addr, value)
+	}
+}
+
+func (p *PDP8) notifyMemoryWrite(addr uint, oldValue uint, newValue uint) {
+	for _, o := range p.observers {
+		o.MemoryWrite(addr, oldValue, newValue)
+	}
+}
+
+func (p *PDP8) notifyIOT(pc uint, ir uint) {
+	for _, o := range p.observers {
+		o.IOT(pc, ir)
+	}
+}
+
+func (p *PDP8) notifyInterruptTaken(pc uint) {
+	for _, o := range p.observers {
+		o.InterruptTaken(pc)
+	}
+}
+
+func (p *PDP8) notifyHalted(pc uint) {
+	for _, o := range p.observers {
+		o.Halted(pc)
+	}
+}
@@ -0,0 +1,176 @@
+package pdp8
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Records each callback as a string
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) InstructionFetched(pc uint, ir uint) {
+	o.events = append(o.events, fmt.Sprintf("fetched %04o %04o", pc, ir))
+}
+
+func (o *recordingObserver) InstructionExecuted(pc uint, ir uint, ea uint) {
+	o.events = append(o.events, fmt.Sprintf("executed %04o %04o %04o", pc, ir, ea))
+}
+
+func (o *recordingObserver) MemoryRead(addr uint, value uint) {
+	o.events = append(o.events, fmt.Sprintf("read %04o %04o", addr, value))
+}
+
+func (o *recordingObserver) MemoryWrite(addr uint, oldValue uint, newValue uint) {
+	o.events = append(o.events, fmt.Sprintf("write %04o %04o %04o", addr, oldValue, newValue))
+}
+
+func (o *recordingObserver) IOT(pc uint, ir uint) {
+	o.events = append(o.events, fmt.Sprintf("iot %04o %04o", pc, ir))
+}
+
+func (o *recordingObserver) InterruptTaken(pc uint) {
+	o.events = append(o.events, fmt.Sprintf("interrupt %04o", pc))
+}
+
+func (o *recordingObserver) Halted(pc uint) {
+	o.events = append(o.events, fmt.Sprintf("halted %04o", pc))
+}
+
+func TestObserver(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o1410, // TAD I 10
+		0o201: 0o3211, // DCA 211
+		0o202: 0o6046, // TLS
+		0o203: 0o7402, // HLT
+		0o010: 0o0211,
+		0o212: 0o0005,
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	o := &recordingObserver{}
+	p.AddObserver(o)
+
+	hlt, _, err := p.Run(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hlt {
+		t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
+	}
+
+	want := []string{
+		"fetched 0200 1410",
+		"read 0010 0211",
+		"write 0010 0211 0212",
+		"read 0010 0212",
+		"read 0212 0005",
+		"executed 0200 1410 0212",
+		"fetched 0201 3211",
+		"write 0211 0000 0005",
+		"executed 0201 3211 0211",
+		"fetched 0202 6046",
+		"iot 0202 6046",
+		"executed 0202 6046 0000",
+		"fetched 0203 7402",
+		"executed 0203 7402 0000",
+		"halted 0203",
+	}
+	if !reflect.DeepEqual(o.events, want) {
+		t.Errorf("got: %q, want: %q", o.events, want)
+	}
+}
+
+func TestObserver_InterruptTaken(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6001, // ION
+		0o201: 0o6046, // TLS
+		0o202: 0o5202, // JMP 202
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	o := &recordingObserver{}
+	p.AddObserver(o)
+
+	if _, err := p.RunUntil(InterruptTaken(), CycleBudget(500)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"write 0000 0000 0202",
+		"interrupt 0202",
+	}
+	got := o.events[len(o.events)-2:]
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestRemoveObserver(t *testing.T) {
+	o1 := &recordingObserver{}
+	o2 := &recordingObserver{}
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddObserver(o1)
+	p.AddObserver(o2)
+
+	p.RemoveObserver(o1)
+	if len(p.observers) != 1 || p.observers[0] != o2 {
+		t.Fatalf("observers got: %v, want: [%v]", p.observers, o2)
+	}
+	p.RemoveObserver(o2)
+	if p.observers != nil {
+		t.Errorf("observers got: %v, want: nil", p.observers)
+	}
+}
+
+// sliceObserver isn't comparable because of the slice
+type sliceObserver struct {
+	NopObserver
+	events []string
+}
+
+func TestRemoveObserver_not_comparable(t *testing.T) {
+	o1 := sliceObserver{}
+	o2 := &recordingObserver{}
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddObserver(o1)
+	p.AddObserver(o2)
+
+	p.RemoveObserver(sliceObserver{})
+	p.RemoveObserver(o2)
+	if len(p.observers) != 1 {
+		t.Errorf("observers got: %v, want: [%v]", p.observers, o1)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Options to configure a machine created by New
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Option configures the machine created by New
+type Option func(*config) error
+
+// config is built up by the options passed to New
+type config struct {
+	model     Model
+	memSize   uint
+	pc        uint
+	sr        uint
+	fill      uint
+	devices   []configDevice
+	logger    *slog.Logger
+	observers []Observer
+	history   int
+}
+
+// configDevice is a device and the device numbers it is to be attached at
+type configDevice struct {
+	d       Device
+	numbers []int
+}
+
+// WithModel sets the model of PDP-8 to emulate
+func WithModel(m Model) Option {
+	return func(c *config) error {
+		if m < ModelPDP8 || m > ModelPDP8E {
+			return fmt.Errorf("unknown model: %s", m)
+		}
+		c.model = m
+		return nil
+	}
+}
+
+// WithMemorySize sets the number of words of memory.  This must be
+// a multiple of 4K up to 32K.  Memory extension is enabled if this is
+// more than 4K.
+func WithMemorySize(words uint) Option {
+	return func(c *config) error {
+		if words == 0 || words%fieldSize != 0 || words > 8*fieldSize {
+			return fmt.Errorf("invalid memory size: %d", words)
+		}
+		c.memSize = words
+		return nil
+	}
+}
+
+// WithPC sets the initial PC.  Bits 12-14 of pc set the
+// instruction field.
+func WithPC(pc uint) Option {
+	return func(c *config) error {
+		if pc > 0o77777 {
+			return fmt.Errorf("invalid PC: %o", pc)
+		}
+		c.pc = pc
+		return nil
+	}
+}
+
+// WithSR sets the initial Switch Register
+func WithSR(sr uint) Option {
+	return func(c *config) error {
+		if sr > 0o7777 {
+			return fmt.Errorf("invalid SR: %o", sr)
+		}
+		c.sr = sr
+		return nil
+	}
+}
+
+// WithMemoryFill sets every word of memory to v, such as a HLT
+// to catch stray jumps
+func WithMemoryFill(v uint) Option {
+	return func(c *config) error {
+		if v > 0o7777 {
+			return fmt.Errorf("invalid memory fill: %o", v)
+		}
+		c.fill = v
+		return nil
+	}
+}
+
+// WithDevice attaches a device.  If deviceNumbers are passed the device
+// is attached at these instead of the device numbers it normally uses.
+// They are matched in order with the device numbers it normally uses,
+// so a second TTY could be attached at 40 and 41 instead of 03 and 04.
+func WithDevice(d Device, deviceNumbers ...int) Option {
+	return func(c *config) error {
+		if d == nil {
+			return errors.New("device is nil")
+		}
+		c.devices = append(c.devices, configDevice{d: d, numbers: deviceNumbers})
+		return nil
+	}
+}
+
+// WithLogger sets the logger used for diagnostics
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) error {
+		if l == nil {
+			return errors.New("logger is nil")
+		}
+		c.logger = l
+		return nil
+	}
+}
+
+// WithObserver attaches an observer
+func WithObserver(o Observer) Option {
+	return func(c *config) error {
+		if o == nil {
+			return errors.New("observer is nil")
+		}
+		c.observers = append(c.observers, o)
+		return nil
+	}
+}
+
+// WithHistory keeps a history of the last n instructions executed
+// so that they can be inspected and undone with StepBack
+func WithHistory(n int) Option {
+	return func(c *config) error {
+		if n < 0 {
+			return fmt.Errorf("invalid history size: %d", n)
+		}
+		c.history = n
+		return nil
+	}
+}
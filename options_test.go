@@ -0,0 +1,131 @@
+package pdp8
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew_options(t *testing.T) {
+	rw := newDummyReadWriter()
+	ttyOut := &bytes.Buffer{}
+	tty := NewTTY(rw, ttyOut)
+	defer tty.Close()
+	o := &recordingObserver{}
+
+	p, err := New(
+		WithModel(ModelPDP8E),
+		WithMemorySize(8192),
+		WithPC(0o10200),
+		WithSR(0o1234),
+		WithMemoryFill(0o7402),
+		WithDevice(tty, 0o40, 0o41),
+		WithLogger(slog.New(slog.NewTextHandler(rw, nil))),
+		WithObserver(o),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := p.State()
+	if p.Model() != ModelPDP8E || len(p.mem) != 8192 ||
+		s.PC != 0o200 || s.IF != 1 || s.IB != 1 || s.SR != 0o1234 {
+		t.Fatalf("got Model: %s, memory: %d, PC: %04o, IF: %o, IB: %o, SR: %04o, want: Model: PDP-8/E, memory: 8192, PC: 0200, IF: 1, IB: 1, SR: 1234",
+			p.Model(), len(p.mem), s.PC, s.IF, s.IB, s.SR)
+	}
+	for addr, v := range p.mem {
+		if v != 0o7402 {
+			t.Fatalf("got mem[%05o]: %04o, want: 7402", addr, v)
+		}
+	}
+
+	testRoutine := map[uint]uint{
+		0o10200: 0o7604, // CLA OSR
+		0o10201: 0o6416, // TLS on device 41, 04 for the TTY
+		0o10202: 0o6411, // TSF on device 41
+		0o10203: 0o5202, // JMP 202
+		0o10204: 0o7402, // HLT
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+
+	stop, err := p.RunUntil(Halted(), CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stop.Halted || stop.State.PC-1 != 0o204 {
+		t.Errorf("HLT - got PC: %04o, want PC: 0204", stop.State.PC-1)
+	}
+	if !bytes.Equal(ttyOut.Bytes(), []byte{0o34}) {
+		t.Errorf("got output: %v, want: [28]", ttyOut.Bytes())
+	}
+	if len(o.events) == 0 {
+		t.Error("observer wasn't attached")
+	}
+}
+
+// noNumbersDevice doesn't use any device numbers
+type noNumbersDevice struct {
+	failingDevice
+}
+
+func (d *noNumbersDevice) DeviceNumbers() []int {
+	return nil
+}
+
+func TestNew_invalid_options(t *testing.T) {
+	rw := newDummyReadWriter()
+	tty1 := NewTTY(rw, rw)
+	defer tty1.Close()
+	tty2 := NewTTY(rw, rw)
+	defer tty2.Close()
+
+	cases := []struct {
+		opts    []Option
+		wantErr []string
+	}{
+		{[]Option{WithModel(Model(20))}, []string{"unknown model: Model(20)"}},
+		{[]Option{WithMemorySize(5000)}, []string{"invalid memory size: 5000"}},
+		{[]Option{WithMemorySize(0o100000)}, []string{}},
+		{[]Option{WithMemorySize(0o110000)}, []string{"invalid memory size: 36864"}},
+		{[]Option{WithPC(0o10200)}, []string{"PC: 10200, outside of memory"}},
+		{[]Option{WithSR(0o10000)}, []string{"invalid SR: 10000"}},
+		{[]Option{WithMemoryFill(0o10000)}, []string{"invalid memory fill: 10000"}},
+		{[]Option{WithDevice(tty1), WithDevice(tty2)},
+			[]string{"device number conflict: 03"}},
+		{[]Option{WithDevice(tty1, 0o40)},
+			[]string{"device needs 2 device numbers, got: 1"}},
+		{[]Option{WithDevice(tty1, 0o40, 0o40)},
+			[]string{"device number conflict: 40"}},
+		{[]Option{WithDevice(tty1, 0o0, 0o100)},
+			[]string{"invalid device number: 00"}},
+		{[]Option{WithMemorySize(8192), WithDevice(tty1, 0o21, 0o22)},
+			[]string{"device number used by memory extension: 21"}},
+		{[]Option{WithDevice(tty1, 0o21, 0o22)}, []string{}},
+		{[]Option{WithDevice(&noNumbersDevice{})},
+			[]string{"device has no device numbers"}},
+		{[]Option{WithSR(0o10000), WithMemorySize(5000)},
+			[]string{"invalid SR: 10000", "invalid memory size: 5000"}},
+	}
+
+	for _, c := range cases {
+		_, err := New(c.opts...)
+		if len(c.wantErr) == 0 {
+			if err != nil {
+				t.Errorf("New: %s", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("New didn't return an error, want: %s", c.wantErr)
+			continue
+		}
+		for _, wantErr := range c.wantErr {
+			if !strings.Contains(err.Error(), wantErr) {
+				t.Errorf("got error: %s, want: %s", err, wantErr)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * A PC8-E high-speed paper tape reader and punch
+ *
+ * The reader is device 01 and reads 300 characters a second.  The
+ * punch is device 02 and punches 50 characters a second.  Rather than
+ * using a clock, when the reader or punch is started the cycle at
+ * which it will finish is worked out and its flag is set when the
+ * device is next looked at on or after that cycle.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The number of cycles to read or punch a character
+const (
+	pc8eReaderCycles = instructionsPerSecond / 300
+	pc8ePunchCycles  = instructionsPerSecond / 50
+)
+
+type PC8E struct {
+	p *PDP8 // The machine attached to
+
+	readerBuffer uint   // The character last read
+	readerFlag   bool   // Reader has read a character
+	readerBusy   bool   // Reader is reading a character
+	readerDone   uint64 // The cycle the reader finishes reading
+	readerEOF    bool   // True if no more tape to read
+	readerPos    int    // The position of the reader on the tape
+	punchFlag    bool   // Punch is ready for a new character
+	punchBusy    bool   // Punch is punching a character
+	punchDone    uint64 // The cycle the punch finishes punching
+	intEnable    bool   // Whether the reader and punch flags cause interrupts
+
+	tapein  io.Reader // Paper tape reader input
+	tapeout io.Writer // Paper tape punch output
+}
+
+// NewPC8E returns a reader and punch without any tapes attached
+func NewPC8E() *PC8E {
+	return &PC8E{intEnable: true}
+}
+
+// Attach a punched tape to the reader
+func (pc *PC8E) ReaderAttachTape(tapein io.Reader) {
+	pc.tapein = tapein
+	pc.readerEOF = false
+	pc.readerPos = 0
+}
+
+// Returns whether the reader has reached the end of the tape
+func (pc *PC8E) ReaderIsEOF() bool {
+	return pc.readerEOF
+}
+
+// ReaderPos returns the position on the paper tape, starting at 0
+func (pc *PC8E) ReaderPos() int {
+	return pc.readerPos
+}
+
+// Attach a punched tape to the punch, nil removes it
+func (pc *PC8E) PunchAttachTape(tapeout io.Writer) {
+	pc.tapeout = tapeout
+}
+
+func (pc *PC8E) Attach(p *PDP8) {
+	pc.p = p
+}
+
+// Closes device but doesn't close any tapes attached to it
+func (pc *PC8E) Close() error {
+	return nil
+}
+
+func (pc *PC8E) DeviceNumbers() []int {
+	return []int{0o1, 0o2}
+}
+
+func (pc *PC8E) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "PC8-E",
+		Description: "High-speed paper tape reader and punch",
+	}
+}
+
+// Reset clears the flags, the reader buffer and stops the reader
+// and punch.  Any tapes stay attached.
+func (pc *PC8E) Reset() {
+	pc.CAF()
+	pc.readerBuffer = 0
+	pc.readerBusy = false
+	pc.punchBusy = false
+}
+
+// CAF clears the flags and enables interrupts
+func (pc *PC8E) CAF() {
+	pc.readerFlag = false
+	pc.punchFlag = false
+	pc.intEnable = true
+}
+
+// Interrupt returns if the reader or punch flag is set
+func (pc *PC8E) Interrupt() (bool, error) {
+	err := pc.update()
+	return pc.intEnable && (pc.readerFlag || pc.punchFlag), err
+}
+
+// update finishes reading or punching if the reader or punch
+// has had long enough
+func (pc *PC8E) update() error {
+	now := attachedCycles(pc.p)
+	if pc.readerBusy && now >= pc.readerDone {
+		pc.readerBusy = false
+		if err := pc.read(); err != nil {
+			return err
+		}
+	}
+	if pc.punchBusy && now >= pc.punchDone {
+		pc.punchBusy = false
+		pc.punchFlag = true
+	}
+	return nil
+}
+
+// read reads a character from the tape into the reader buffer.  The
+// flag isn't set if there isn't a tape or the end of tape is reached.
+func (pc *PC8E) read() error {
+	if pc.tapein == nil || pc.readerEOF {
+		return nil
+	}
+	b := make([]byte, 1)
+	n, err := pc.tapein.Read(b)
+	if err == io.EOF {
+		pc.readerEOF = true
+	} else if err != nil {
+		return fmt.Errorf("PC8-E: %w", err)
+	}
+	if n == 1 {
+		pc.readerBuffer = uint(b[0])
+		pc.readerFlag = true
+		pc.readerPos++
+	}
+	return nil
+}
+
+// punch punches the lower 8 bits of lac and starts the punch
+func (pc *PC8E) punch(lac uint) error {
+	if pc.tapeout != nil {
+		n, err := pc.tapeout.Write([]byte{byte(lac & 0o377)})
+		if err != nil {
+			return fmt.Errorf("PC8-E: %w", err)
+		}
+		if n != 1 {
+			return errors.New("PC8-E: write failed")
+		}
+	}
+	pc.punchBusy = true
+	pc.punchDone = attachedCycles(pc.p) + pc8ePunchCycles
+	return nil
+}
+
+// IOT returns PC, LAC, error
+func (pc *PC8E) IOT(ir uint, pcReg uint, lac uint) (uint, uint, error) {
+	var err error
+
+	if err := pc.update(); err != nil {
+		return pcReg, lac, err
+	}
+
+	// Operations are executed from right bit to left
+	device := (ir >> 3) & 0o77
+	switch device {
+	case 0o1: // Reader
+		switch ir & 0o7 {
+		case 0o0: // RPE - Set interrupt enable
+			pc.intEnable = true
+		case 0o1: // RSF - Skip if flag set
+			if pc.readerFlag {
+				pcReg = mask(pcReg + 1)
+			}
+		}
+		if (ir & 0o2) == 0o2 { // RRB - OR buffer into AC and clear flag
+			lac |= pc.readerBuffer
+			pc.readerFlag = false
+		}
+		if (ir & 0o4) == 0o4 { // RFC - Clear flag and fetch character
+			pc.readerFlag = false
+			pc.readerBusy = true
+			pc.readerDone = attachedCycles(pc.p) + pc8eReaderCycles
+		}
+	case 0o2: // Punch
+		switch ir & 0o7 {
+		case 0o0: // PCE - Clear interrupt enable
+			pc.intEnable = false
+		case 0o1: // PSF - Skip if flag set
+			if pc.punchFlag {
+				pcReg = mask(pcReg + 1)
+			}
+		}
+		if (ir & 0o2) == 0o2 { // PCF - Clear flag
+			pc.punchFlag = false
+		}
+		if (ir & 0o4) == 0o4 { // PPC - Punch character
+			err = pc.punch(lac)
+		}
+	}
+	return pcReg, lac, err
+}
@@ -0,0 +1,189 @@
+package pdp8
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPC8E_reader(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6014, // RFC
+		0o201: 0o6011, // RSF
+		0o202: 0o5201, // JMP 201
+		0o203: 0o6016, // RRB RFC
+		0o204: 0o7402, // HLT
+	}
+
+	pc := NewPC8E()
+	pc.ReaderAttachTape(bytes.NewReader([]byte{0o273, 0o12}))
+	p, err := New(WithDevice(pc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(5000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.lac != 0o273 {
+		t.Errorf("got LAC: %05o, want: %05o", p.lac, 0o273)
+	}
+	if c := p.Cycles(); c < pc8eReaderCycles || c > pc8eReaderCycles+5 {
+		t.Errorf("got cycles: %d, want: about %d", c, pc8eReaderCycles)
+	}
+	if pc.ReaderPos() != 1 {
+		t.Errorf("got reader pos: %d, want: 1", pc.ReaderPos())
+	}
+}
+
+func TestPC8E_punch(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6026, // PLS
+		0o201: 0o6021, // PSF
+		0o202: 0o5201, // JMP 201
+		0o203: 0o7001, // IAC
+		0o204: 0o6026, // PLS
+		0o205: 0o7402, // HLT
+	}
+
+	tape := &bytes.Buffer{}
+	pc := NewPC8E()
+	pc.PunchAttachTape(tape)
+	p, err := New(WithDevice(pc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.lac = 0o10377
+	if _, err := p.RunUntil(Halted(), CycleBudget(20000)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tape.Bytes(), []byte{0o377, 0o0}; !bytes.Equal(got, want) {
+		t.Errorf("got tape: %o, want: %o", got, want)
+	}
+	if c := p.Cycles(); c < pc8ePunchCycles || c > pc8ePunchCycles+5 {
+		t.Errorf("got cycles: %d, want: about %d", c, pc8ePunchCycles)
+	}
+}
+
+func TestPC8E_interrupt(t *testing.T) {
+	cases := []struct {
+		ir            uint
+		wantInterrupt bool
+	}{
+		{ir: 0o6010, wantInterrupt: true},  // RPE
+		{ir: 0o6020, wantInterrupt: false}, // PCE
+	}
+	for _, c := range cases {
+		pc := NewPC8E()
+		pc.ReaderAttachTape(bytes.NewReader([]byte{0o1}))
+		p, err := New(WithDevice(pc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.mem[0o200] = c.ir
+		p.mem[0o201] = 0o6014 // RFC
+		p.mem[0o202] = 0o6001 // ION
+		p.mem[0o203] = 0o5203 // JMP 203
+		p.mem[0o1] = 0o7402   // HLT
+		hlt, _, err := p.Run(pc8eReaderCycles + 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hlt != c.wantInterrupt {
+			t.Errorf("%04o - got interrupt: %t, want: %t",
+				c.ir, hlt, c.wantInterrupt)
+		}
+	}
+}
+
+func TestLoadHighSpeedRIMTape(t *testing.T) {
+	mem := map[uint]uint{0o200: 0o7001, 0o201: 0o5200, 0o3777: 0o1234}
+
+	// Leader, origin and data, trailer
+	tape := []byte{0o200, 0o200}
+	for addr, v := range mem {
+		tape = append(tape, byte(0o100|addr>>6), byte(addr&0o77),
+			byte(v>>6), byte(v&0o77))
+	}
+	tape = append(tape, 0o200, 0o200)
+	filename := filepath.Join(t.TempDir(), "test.rim")
+	if err := os.WriteFile(filename, tape, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pc := NewPC8E()
+	p, err := New(WithDevice(pc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LoadHighSpeedRIMTape(pc, filename); err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range mem {
+		if got := p.mem[addr]; got != want {
+			t.Errorf("mem[%04o] got: %04o, want: %04o", addr, got, want)
+		}
+	}
+}
+
+func TestLoadHighSpeedRIMTape_not_attached(t *testing.T) {
+	pc := NewPC8E()
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "test.rim")
+	if err := os.WriteFile(filename, []byte{0o200, 0o200}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LoadHighSpeedRIMTape(pc, filename); err == nil {
+		t.Error("LoadHighSpeedRIMTape: no error for a PC8-E not attached")
+	}
+
+	// Attached at other device numbers the loader's IOTs won't reach it
+	p, err = New(WithDevice(pc, 0o40, 0o41))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LoadHighSpeedRIMTape(pc, filename); err == nil {
+		t.Error("LoadHighSpeedRIMTape: no error for a PC8-E at device 40")
+	}
+}
+
+// Loads a MAINDEC tape with the BIN loader using the high-speed
+// reader and checks that memory is the same as when using the
+// low-speed reader
+func TestLoadBINTape_high_speed_maindec(t *testing.T) {
+	t.Parallel()
+	filename := filepath.Join("fixtures", "maindec-08-d01a-pb.bin")
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+	p1, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadBINTape(t, p1, tty, filename)
+
+	pc := NewPC8E()
+	p2, err := New(WithDevice(pc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadHighSpeedBINTape(t, p2, pc, filename)
+
+	// The loaders are at the top of memory
+	for addr := uint(0); addr < 0o7600; addr++ {
+		if p1.mem[addr] != p2.mem[addr] {
+			t.Fatalf("mem[%04o] got: %04o, want: %04o",
+				addr, p2.mem[addr], p1.mem[addr])
+		}
+	}
+}
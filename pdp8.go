@@ -10,34 +10,107 @@ package pdp8
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 )
 
-const memSize = 4096
+// The number of words in each memory field
+const fieldSize = 4096
 
 type PDP8 struct {
 	// NOTE: Using uint rather than int because of right shifting
 	// TODO: consider creating a word type to better encapsulate this?
-	mem           [memSize]uint // Memory
-	pc            uint          // Program counter
-	ir            uint          // Instruction register
-	sr            uint          // Switch register
-	lac           uint          // Accumulator register 13th bit is Link flag
-	mq            uint          // Multiplier Quotient
-	ien           bool          // Whether interrupts are enabled
-	pendingIen    bool          // If turning on interrupts is pending
-	devices       []device      // Devices for IOT
-	deviceNumbers []int         // The device numbers currently registered
+	mem        []uint            // Memory
+	model      Model             // The model being emulated
+	pc         uint              // Program counter
+	ir         uint              // Instruction register
+	sr         uint              // Switch register
+	lac        uint              // Accumulator register 13th bit is Link flag
+	mq         uint              // Multiplier Quotient
+	ifr        uint              // Instruction field
+	dfr        uint              // Data field
+	ib         uint              // Instruction field buffer
+	sf         uint              // Save field, IF and DF saved on interrupt
+	intInhibit bool              // Interrupts inhibited until JMP/JMS after CIF
+	ien        bool              // Whether interrupts are enabled
+	pendingIen bool              // If turning on interrupts is pending
+	cycles     uint64            // The number of instructions executed
+	events     []*event          // Events scheduled to run, in cycle order
+	devices    []*attachedDevice // Devices in the order attached
+	iotDevices [0o100]deviceSlot // Devices by device number for IOT
+	observers  []Observer        // Observers notified of activity, nil if none
+	breaks     *breakpoints      // Breakpoints and watchpoints, nil if none
+	history    *history          // Instructions executed, nil if not kept
+	inBreak    bool              // Whether events are making data breaks
+	logger     *slog.Logger      // Where diagnostics are logged
 }
 
-func New() *PDP8 {
+// State is a snapshot of the registers
+type State struct {
+	PC  uint // Program counter
+	IR  uint // Instruction register
+	AC  uint // Accumulator
+	L   uint // Link
+	MQ  uint // Multiplier Quotient
+	SR  uint // Switch register
+	IF  uint // Instruction field
+	DF  uint // Data field
+	IB  uint // Instruction field buffer
+	SF  uint // Save field
+	ION bool // Whether interrupts are enabled
+}
+
+// New creates a machine configured by opts.  If no options are passed
+// it will be a 4K PDP-8/I with the PC at 0200.
+func New(opts ...Option) (*PDP8, error) {
+	c := &config{
+		model:   ModelPDP8I,
+		memSize: fieldSize,
+		pc:      0o200,
+		logger:  discardLogger,
+	}
+	var errs []error
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if c.pc >= c.memSize {
+		return nil, fmt.Errorf("PC: %05o, outside of memory", c.pc)
+	}
+
 	p := &PDP8{}
-	p.pc = 0o200
-	p.sr = 0
+	p.model = c.model
+	p.mem = make([]uint, c.memSize)
+	for addr := range p.mem {
+		p.mem[addr] = c.fill
+	}
+	p.logger = c.logger
+	p.pc = mask(c.pc)
+	p.ifr = c.pc >> 12
+	p.ib = p.ifr
+	p.sr = c.sr
 	p.lac = 0
-	return p
+	p.SetHistorySize(c.history)
+
+	for _, cd := range c.devices {
+		if err := p.addDevice(cd.d, cd.numbers); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, o := range c.observers {
+		p.AddObserver(o)
+	}
+	return p, nil
 }
 
 // Returns the lower 12-bits
@@ -51,12 +124,13 @@ func lmask(w uint) uint {
 	return w & 0o17777
 }
 
+// Returns the holes that would be punched on a paper tape for n
 // TODO: Decide if to use this
-func printPunchHoles(n uint) {
+func punchHoles(n uint) (string, error) {
 	if n > 255 {
-		panic(fmt.Sprintf("punch num too big: %d", n))
+		return "", fmt.Errorf("punch num too big: %d", n)
 	}
-	fmt.Printf("%05b %03b\r\n", (n&0o370)>>3, n&0o7)
+	return fmt.Sprintf("%05b %03b", (n&0o370)>>3, n&0o7), nil
 }
 
 // Load paper tape in RIM format
@@ -82,6 +156,10 @@ func (p *PDP8) LoadRIMTape(tty *TTY, filename string) error {
 		0o7777: 0o0,
 	}
 
+	// The loader is run from field 0
+	p.ifr = 0
+	p.ib = 0
+	p.dfr = 0
 	for addr, v := range rimLowSpeedLoader {
 		p.mem[addr] = v
 	}
@@ -101,23 +179,27 @@ func (p *PDP8) LoadRIMTape(tty *TTY, filename string) error {
 	// Start the punched tape reader
 	tty.ReaderStart()
 
-	// Run and panic if HLT
+	// Run and return an error if HLT
 	runNoHlt := func(p *PDP8, cycles int) error {
 		hlt, _, err := p.Run(cycles)
 		if err != nil {
-			return err
+			return &LoaderError{Pos: tty.ReaderPos(), Err: err}
 		}
 
 		// TODO: This won't work with autostarting RIM tapes
 		if hlt {
-			panic(fmt.Sprintf("HLT at PC: %04o", p.pc-1))
+			return &LoaderError{
+				Pos: tty.ReaderPos(),
+				Err: &HaltError{PC: mask(p.pc - 1)},
+			}
 		}
 		return nil
 	}
 
 	for !tty.ReaderIsEOF() {
 		// Run RIM loader to load the paper tape
-		if runNoHlt(p, 100); err != nil {
+		if err := runNoHlt(p, 100); err != nil {
+			tty.ReaderStop()
 			return err
 		}
 	}
@@ -126,13 +208,86 @@ func (p *PDP8) LoadRIMTape(tty *TTY, filename string) error {
 
 	// Run another time in case finishes between EOF and handling last
 	// value read
-	if runNoHlt(p, 10000); err != nil {
+	if err := runNoHlt(p, 10000); err != nil {
 		return err
 	}
 
 	// TODO: This won't work with autostarting RIM tapes
 	if !tty.ReaderIsEOF() || !(p.pc == 0o7756 || p.pc == 0o7760) {
-		return fmt.Errorf("RIM loader didn't finish, PC: %04o", p.pc)
+		return &LoaderError{
+			Pos: tty.ReaderPos(),
+			Err: fmt.Errorf("RIM loader didn't finish, PC: %04o", p.pc),
+		}
+	}
+	return nil
+}
+
+// Load paper tape in RIM format using the high-speed reader.  pc
+// must be attached to p at device 01.
+func (p *PDP8) LoadHighSpeedRIMTape(pc *PC8E, filename string) error {
+	if slot := p.iotDevices[0o1]; slot.a == nil || slot.a.d != pc ||
+		slot.native != 0o1 {
+		return errors.New("PC8-E not attached at device 01")
+	}
+
+	rimHighSpeedLoader := map[uint]uint{
+		0o7756: 0o6014,
+		0o7757: 0o6011,
+		0o7760: 0o5357,
+		0o7761: 0o6016,
+		0o7762: 0o7106,
+		0o7763: 0o7006,
+		0o7764: 0o7510,
+		0o7765: 0o5374,
+		0o7766: 0o7006,
+		0o7767: 0o6011,
+		0o7770: 0o5367,
+		0o7771: 0o6016,
+		0o7772: 0o7420,
+		0o7773: 0o3776,
+		0o7774: 0o3376,
+		0o7775: 0o5357,
+		0o7776: 0o0,
+		0o7777: 0o0,
+	}
+
+	// The loader is run from field 0
+	p.ifr = 0
+	p.ib = 0
+	p.dfr = 0
+	for addr, v := range rimHighSpeedLoader {
+		p.mem[addr] = v
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pc.ReaderAttachTape(bufio.NewReader(f))
+
+	// Start of RIM loader
+	p.pc = 0o7756
+
+	for !pc.ReaderIsEOF() {
+		hlt, _, err := p.Run(1000)
+		if err != nil {
+			return &LoaderError{Pos: pc.ReaderPos(), Err: err}
+		}
+		if hlt {
+			return &LoaderError{
+				Pos: pc.ReaderPos(),
+				Err: &HaltError{PC: mask(p.pc - 1)},
+			}
+		}
+	}
+
+	if !(p.pc == 0o7757 || p.pc == 0o7760) {
+		return &LoaderError{
+			Pos: pc.ReaderPos(),
+			Err: fmt.Errorf("RIM loader didn't finish, PC: %04o", p.pc),
+		}
 	}
 	return nil
 }
@@ -142,6 +297,7 @@ func (p *PDP8) Load(filename string) error {
 	var n int
 	var c uint
 	var addr uint
+	var start uint // The start address of the current block
 	// NOTE: The checksum is the sum of each byte of data
 	// NOTE: NOT each word
 	var checksum uint
@@ -153,6 +309,12 @@ func (p *PDP8) Load(filename string) error {
 	}
 	defer f.Close()
 
+	// loaderError wraps err with the position on the tape
+	loaderError := func(err error) error {
+		pos, _ := f.Seek(0, io.SeekCurrent)
+		return &LoaderError{Pos: int(pos), Err: err}
+	}
+
 	// Skip until run-in found
 	for {
 		n, err = f.Read(b)
@@ -164,12 +326,12 @@ func (p *PDP8) Load(filename string) error {
 	if err == io.EOF {
 		return nil
 	} else if err != nil {
-		return err
+		return loaderError(err)
 	}
 	for {
 		_, err = f.Read(b)
 		if err != nil {
-			return err
+			return loaderError(err)
 		}
 		c = uint(b[0])
 
@@ -181,7 +343,7 @@ func (p *PDP8) Load(filename string) error {
 		hi := c << 6 // High 6 bits
 		_, err = f.Read(b)
 		if err != nil {
-			return err
+			return loaderError(err)
 		}
 		c = uint(b[0])
 		c = hi | (c & 0o77) // Make 12-bit word
@@ -189,7 +351,7 @@ func (p *PDP8) Load(filename string) error {
 		// Look for run-out, to ignore word before it as being a checksum
 		_, err = f.Read(b)
 		if err != nil {
-			return err
+			return loaderError(err)
 		}
 		d := uint(b[0])
 
@@ -201,7 +363,7 @@ func (p *PDP8) Load(filename string) error {
 		// Not run-out word so unget char
 		_, err = f.Seek(-1, 1)
 		if err != nil {
-			return err
+			return loaderError(err)
 		}
 
 		// Process word
@@ -209,10 +371,11 @@ func (p *PDP8) Load(filename string) error {
 		// Else it is a word to put at the current address
 		if (c & 0o10000) != 0 {
 			if addr != 0 {
-				fmt.Printf("-%04o", addr-1)
+				p.logger.Debug("loaded block", octal("start", start),
+					octal("end", addr-1))
 			}
 			addr = mask(c)
-			fmt.Printf(" %04o", addr)
+			start = addr
 		} else {
 			p.mem[addr] = c
 			checksum = mask(checksum + c&0o77)
@@ -221,79 +384,142 @@ func (p *PDP8) Load(filename string) error {
 		}
 	}
 
-	// TODO: Should we be showing the checksum and other values, unless
-	// TODO: debug mode is on?
-	fmt.Printf("-%04o\r\n", mask(addr-1))
-	fmt.Printf(" CHECKSUM ")
-	if checksum == mask(c) {
-		fmt.Printf("OK: %04o\r\n", checksum)
-	} else {
-		fmt.Printf("FAIL: %04o, SHOULD BE: %04o\r\n", checksum, mask(c))
-		//		os.Exit(1)
-		// TODO: What to do if fails?
+	p.logger.Debug("loaded block", octal("start", start),
+		octal("end", mask(addr-1)))
+	if checksum != mask(c) {
+		return loaderError(&ChecksumError{Got: checksum, Want: mask(c)})
 	}
-	return nil
-}
-
-func (p *PDP8) AddDevice(d device) error {
-	newDeviceNumbers := d.deviceNumbers()
-	for _, n1 := range newDeviceNumbers {
-		for _, n2 := range p.deviceNumbers {
-			if n1 == n2 {
-				return fmt.Errorf("device number conflict: %02o", n1)
-			}
-		}
-		p.deviceNumbers = append(p.deviceNumbers, n1)
-	}
-	p.devices = append(p.devices, d)
+	p.logger.Debug("checksum OK", octal("checksum", checksum))
 	return nil
 }
 
 // Returns (hlt, cyclesLeft, error)
+// The instruction that halts or hits a breakpoint is counted as a
+// cycle used.
 // TODO: Improve cycle accuracy and return number left/over?
-// TODO: Test cyclesLeft
 func (p *PDP8) Run(cycles int) (bool, int, error) {
 	var err error
 	var hlt bool
-	var isInterrupt bool
 
 	for cycles > 0 {
-		hlt, err = p.Step()
-		if err != nil || hlt {
+		hlt, _, err = p.cycle()
+		var be *BreakError
+		if hlt || errors.As(err, &be) {
+			cycles--
+			break
+		}
+		if err != nil {
 			break
 		}
+		cycles--
+	}
+	return hlt, cycles, err
+}
 
-		if p.ien {
-			for _, d := range p.devices {
-				isInterrupt, err = d.interrupt()
-				if err != nil {
-					break
-				}
-				if isInterrupt {
-					p.mem[0] = p.pc
-					p.pc = 1
-					p.ien = false
-					break
+// cycle executes one instruction, checks for interrupts and then
+// runs any events and checks breakpoints
+// Returns (hlt, interruptTaken, error)
+func (p *PDP8) cycle() (bool, bool, error) {
+	if p.history != nil {
+		p.history.begin(p)
+	}
+	hlt, isInterrupt, err := p.executeCycle()
+	// Events are run before the history entry is finished so that
+	// memory written by data breaks is restored by StepBack
+	p.inBreak = true
+	err = p.runEvents(err)
+	p.inBreak = false
+	if p.history != nil {
+		p.history.end(p, isInterrupt)
+	}
+	if p.breaks != nil {
+		err = p.breaks.check(err)
+	}
+	return hlt, isInterrupt, err
+}
+
+// executeCycle executes one instruction and then checks for interrupts
+// Returns (hlt, interruptTaken, error)
+func (p *PDP8) executeCycle() (bool, bool, error) {
+	var isInterrupt bool
+
+	hlt, err := p.step()
+	p.cycles++
+	if err != nil || hlt {
+		return hlt, false, err
+	}
+
+	if p.ien && !p.intInhibit {
+		for _, a := range p.devices {
+			isInterrupt, err = a.d.Interrupt()
+			if err != nil {
+				return false, false, &DeviceError{
+					Device: uint(a.numbers[0]),
+					PC:     p.pc,
+					IR:     p.ir,
+					Err:    err,
 				}
 			}
+			if isInterrupt {
+				p.interrupt()
+				break
+			}
 		}
+	}
 
-		// The effect of ION is delayed by one instruction
-		// TODO: test this
-		if p.pendingIen {
-			p.ien = true
-			p.pendingIen = false
-		}
+	// The effect of ION is delayed by one instruction
+	// TODO: test this
+	if p.pendingIen {
+		p.ien = true
+		p.pendingIen = false
+	}
 
-		cycles--
+	return false, isInterrupt, nil
+}
+
+// interrupt saves the fields and PC and then jumps to location 1
+// of field 0
+func (p *PDP8) interrupt() {
+	p.sf = p.ifr<<3 | p.dfr
+	p.ifr = 0
+	p.ib = 0
+	p.dfr = 0
+	p.writeMem(0, p.pc)
+	if p.observers != nil {
+		p.notifyInterruptTaken(p.pc)
 	}
-	return hlt, cycles, err
+	p.pc = 1
+	p.ien = false
 }
 
-// Step Executes one instruction and moves to the next
+// Step Executes one instruction and moves to the next.  Interrupts
+// aren't checked, events aren't run and the cycle isn't counted.
 func (p *PDP8) Step() (bool, error) {
+	if p.history != nil {
+		p.history.begin(p)
+	}
+	hlt, err := p.step()
+	if p.history != nil {
+		p.history.end(p, false)
+	}
+	return hlt, err
+}
+
+// step executes one instruction and tells the history and observers
+func (p *PDP8) step() (bool, error) {
+	pc := p.ifr<<12 | p.pc
 	opCode, opAddr := p.fetch()
-	return p.execute(opCode, opAddr)
+	hlt, err := p.execute(opCode, opAddr)
+	if p.history != nil {
+		p.history.executed(pc, p.ir, opAddr)
+	}
+	if p.observers != nil {
+		p.notifyInstructionExecuted(pc, p.ir, opAddr)
+		if hlt {
+			p.notifyHalted(pc)
+		}
+	}
+	return hlt, err
 }
 
 // Set Program Counter
@@ -306,14 +532,113 @@ func (p *PDP8) SetSR(sr uint) {
 	p.sr = mask(sr)
 }
 
+// State returns a snapshot of the registers
+func (p *PDP8) State() State {
+	return State{
+		PC:  p.pc,
+		IR:  p.ir,
+		AC:  mask(p.lac),
+		L:   p.lac >> 12,
+		MQ:  p.mq,
+		SR:  p.sr,
+		IF:  p.ifr,
+		DF:  p.dfr,
+		IB:  p.ib,
+		SF:  p.sf,
+		ION: p.ien,
+	}
+}
+
+// Cycles returns the number of instructions executed since the machine
+// was created.  Like Run, this counts each instruction as a cycle.
+func (p *PDP8) Cycles() uint64 {
+	return p.cycles
+}
+
 // TODO: rename this
 func (p *PDP8) Cleanup() {
-	fmt.Printf(" PC %04o\r\n", mask(p.pc-1))
+	p.logger.Info("stopped", octal("pc", mask(p.pc-1)))
+}
+
+// readMem returns the word at addr for the instruction being executed
+// Memory that doesn't exist reads as 0
+func (p *PDP8) readMem(addr uint) uint {
+	var v uint
+	if addr < uint(len(p.mem)) {
+		v = p.mem[addr]
+	}
+	if p.observers != nil {
+		p.notifyMemoryRead(addr, v)
+	}
+	return v
+}
+
+// writeMem stores v at addr for the instruction being executed
+// Writes to memory that doesn't exist are ignored
+func (p *PDP8) writeMem(addr uint, v uint) {
+	if addr >= uint(len(p.mem)) {
+		return
+	}
+	if p.observers != nil {
+		p.notifyMemoryWrite(addr, p.mem[addr], v)
+	}
+	if p.history != nil {
+		p.history.write(addr, p.mem[addr], v)
+	}
+	p.mem[addr] = v
+}
+
+// DataBreakRead returns the word at addr, including the field, for a
+// device transferring data directly from memory.  Observers aren't
+// notified because devices also use this to look at memory outside of
+// a transfer, such as the word count before starting one.  Memory that
+// doesn't exist reads as 0.
+func (p *PDP8) DataBreakRead(addr uint) uint {
+	if addr >= uint(len(p.mem)) {
+		return 0
+	}
+	return p.mem[addr]
+}
+
+// DataBreakWrite stores v at addr, including the field, for a device
+// transferring data directly to memory.  Observers are notified and
+// the write is recorded in the history of the cycle it happens in.
+// Writes to memory that doesn't exist are ignored.
+func (p *PDP8) DataBreakWrite(addr uint, v uint) {
+	p.writeMem(addr, mask(v))
+}
+
+// threeCycleBreak transfers words using three cycle data breaks.
+// The word count, as a negative number, is at wcAddr and the current
+// address, one less than the next word, is at wcAddr+1.  Both are in
+// field 0 and are incremented before each word.  fn is passed the
+// address of each word in field until the word count reaches 0 or
+// fn returns false.  It returns the number of words transferred.
+func (p *PDP8) threeCycleBreak(wcAddr uint, field uint, fn func(addr uint) bool) uint {
+	var n uint
+	for {
+		wc := mask(p.DataBreakRead(wcAddr) + 1)
+		p.DataBreakWrite(wcAddr, wc)
+		ca := mask(p.DataBreakRead(wcAddr+1) + 1)
+		p.DataBreakWrite(wcAddr+1, ca)
+		n++
+		if !fn(field<<12|ca) || wc == 0 {
+			return n
+		}
+	}
 }
 
 // fetch returns opCode and opAddr if relevant else 0
+// opAddr includes the field in bits 12-14
 func (p *PDP8) fetch() (opCode uint, opAddr uint) {
-	p.ir = p.mem[p.pc]
+	pc := p.ifr<<12 | p.pc
+	p.ir = 0
+	if pc < uint(len(p.mem)) {
+		p.ir = p.mem[pc]
+	}
+	if p.observers != nil {
+		p.notifyInstructionFetched(pc, p.ir)
+	}
 	opCode = (p.ir >> 9) & 0o7
 	opAddr = 0
 
@@ -323,19 +648,30 @@ func (p *PDP8) fetch() (opCode uint, opAddr uint) {
 			opAddr |= p.pc & 0o7600
 		}
 
+		// Direct addresses are in the instruction field, but
+		// JMP and JMS go to the instruction field buffer which
+		// will have been changed by a CIF
+		field := p.ifr
+		if opCode >= 4 {
+			field = p.ib
+		}
+
 		// If indirect
 		if (p.ir & 0o400) == 0o400 {
+			ptr := p.ifr<<12 | opAddr
 			// If auto increment address
 			if (opAddr & 0o7770) == 0o10 {
-				p.mem[opAddr] = mask(p.mem[opAddr] + 1)
+				p.writeMem(ptr, mask(p.readMem(ptr)+1))
+			}
+			opAddr = p.readMem(ptr)
+			// Indirect operands are in the data field
+			if opCode < 4 {
+				field = p.dfr
 			}
-			opAddr = p.mem[opAddr]
 		}
+		opAddr |= field << 12
 	}
 
-	// TODO: Add a switch to turn this off an on for debugging
-	// fmt.Printf("PC: %04o, IR: %04o, opCode, %04o, opAddr: %04o\n", p.pc, p.mem[p.pc], opCode, opAddr)
-
 	p.pc = mask(p.pc + 1)
 	return opCode, opAddr
 }
@@ -347,22 +683,27 @@ func (p *PDP8) execute(opCode uint, opAddr uint) (bool, error) {
 
 	switch opCode {
 	case 0: // AND
-		p.lac &= p.mem[opAddr] | 0o10000
+		p.lac &= p.readMem(opAddr) | 0o10000
 	case 1: // TAD
-		p.lac = lmask(p.lac + p.mem[opAddr])
+		p.lac = lmask(p.lac + p.readMem(opAddr))
 	case 2: // ISZ
-		p.mem[opAddr] = mask(p.mem[opAddr] + 1)
-		if p.mem[opAddr] == 0 {
+		v := mask(p.readMem(opAddr) + 1)
+		p.writeMem(opAddr, v)
+		if v == 0 {
 			p.pc = mask(p.pc + 1)
 		}
 	case 3: // DCA
-		p.mem[opAddr] = mask(p.lac)
+		p.writeMem(opAddr, mask(p.lac))
 		p.lac &= 0o10000
 	case 4: // JMS
-		p.mem[opAddr] = p.pc
+		p.writeMem(opAddr, p.pc)
 		p.pc = mask(opAddr + 1)
+		p.ifr = p.ib
+		p.intInhibit = false
 	case 5: // JMP
-		p.pc = opAddr
+		p.pc = mask(opAddr)
+		p.ifr = p.ib
+		p.intInhibit = false
 	case 6: // IOT
 		err = p.iot()
 	case 7: // OPR
@@ -373,9 +714,12 @@ func (p *PDP8) execute(opCode uint, opAddr uint) (bool, error) {
 
 // IOT instruction
 func (p *PDP8) iot() error {
-	var err error
 	device := (p.ir >> 3) & 0o77
 	iotOp := p.ir & 0o7
+	iotPC := mask(p.pc - 1)
+	if p.observers != nil {
+		p.notifyIOT(iotPC, p.ir)
+	}
 	switch device {
 	case 0o0: // CPU
 		switch iotOp {
@@ -384,19 +728,73 @@ func (p *PDP8) iot() error {
 		case 0o2: // IOF
 			// IOF is immediate unlike ION
 			p.ien = false
+		case 0o7: // CAF - Clear All Flags
+			if p.model == ModelPDP8E {
+				p.caf()
+			}
 		default:
 			// TODO: Report an unknown op?
 		}
+	case 0o20, 0o21, 0o22, 0o23, 0o24, 0o25, 0o26, 0o27:
+		// Memory extension is only present if more than 4K
+		if len(p.mem) > fieldSize {
+			p.memoryExtension()
+			return nil
+		}
+		fallthrough
 	default:
-		for _, d := range p.devices {
-			p.pc, p.lac, err = d.iot(p.ir, p.pc, p.lac)
-			if err != nil {
-				return err
-				// TODO: add context
+		if err := p.deviceIOT(device, p.ir); err != nil {
+			return &DeviceError{
+				Device: device,
+				PC:     iotPC,
+				IR:     p.ir,
+				Err:    err,
 			}
 		}
 	}
-	return err
+	return nil
+}
+
+// caf clears AC, L, the interrupt system and the flags of every device
+func (p *PDP8) caf() {
+	p.lac = 0
+	p.ien = false
+	p.pendingIen = false
+	p.intInhibit = false
+	for _, a := range p.devices {
+		a.d.CAF()
+	}
+}
+
+// Memory extension IOT instructions
+func (p *PDP8) memoryExtension() {
+	field := (p.ir >> 3) & 0o7
+	switch p.ir & 0o7 {
+	case 0o1: // CDF - Change Data Field
+		p.dfr = field
+	case 0o2: // CIF - Change Instruction Field
+		// Interrupts are inhibited until the next JMP or JMS
+		// so that IF can't change before a jump to the new field
+		p.ib = field
+		p.intInhibit = true
+	case 0o3: // CDF CIF
+		p.dfr = field
+		p.ib = field
+		p.intInhibit = true
+	case 0o4:
+		switch field {
+		case 0o1: // RDF - Read Data Field
+			p.lac |= p.dfr << 3
+		case 0o2: // RIF - Read Instruction Field
+			p.lac |= p.ifr << 3
+		case 0o3: // RIB - Read Interrupt Buffer
+			p.lac |= p.sf
+		case 0o4: // RMF - Restore Memory Field
+			p.ib = p.sf >> 3
+			p.dfr = p.sf & 0o7
+			p.intInhibit = true
+		}
+	}
 }
 
 // OPR instruction (microcoded instructions)
@@ -430,6 +828,10 @@ func (p *PDP8) opr() bool {
 			p.lac = lmask((p.lac >> 12) | (p.lac << 1))
 		case 0o4: // RAL
 			p.lac = lmask((p.lac >> 12) | (p.lac << 1))
+		case 0o2: // BSW - Byte Swap
+			if p.model == ModelPDP8E {
+				p.lac = (p.lac & 0o10000) | (p.lac&0o77)<<6 | (p.lac>>6)&0o77
+			}
 		}
 	} else if (p.ir & 0o1) != 0o1 { // Group 2
 		var sv uint
@@ -456,8 +858,22 @@ func (p *PDP8) opr() bool {
 			return true
 		}
 	} else { // Group 3
-		// The EAE isn't implemented and
-		// we're not emulating a PDP-8/E
+		// The EAE isn't implemented, but the PDP-8/E has an
+		// MQ as standard
+		if p.model == ModelPDP8E {
+			if (p.ir & 0o200) == 0o200 { // CLA
+				p.lac &= 0o10000
+			}
+			switch p.ir & 0o120 {
+			case 0o100: // MQA
+				p.lac |= p.mq
+			case 0o20: // MQL
+				p.mq = mask(p.lac)
+				p.lac &= 0o10000
+			case 0o120: // SWP
+				p.mq, p.lac = mask(p.lac), (p.lac&0o10000)|p.mq
+			}
+		}
 	}
 	return false
 }
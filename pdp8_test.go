@@ -23,8 +23,8 @@ func TestRun_TSF_not_ready_until_TPC_or_TLS(t *testing.T) {
 	tty := NewTTY(rw, rw)
 	defer tty.Close()
 
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -70,8 +70,8 @@ func TestRun_TSF_ready_after_TPC(t *testing.T) {
 	tty := NewTTY(rw, rw)
 	defer tty.Close()
 
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -120,8 +120,8 @@ func TestRun_TSF_ready_after_TLS(t *testing.T) {
 	tty := NewTTY(rw, rw)
 	defer tty.Close()
 
-	p := New()
-	if err := p.AddDevice(tty); err != nil {
+	p, err := New(WithDevice(tty))
+	if err != nil {
 		t.Fatal(err)
 	}
 
@@ -145,3 +145,110 @@ func TestRun_TSF_ready_after_TLS(t *testing.T) {
 		t.Errorf("got: PC: %04o, want: PC: 207", p.pc-1)
 	}
 }
+
+func TestRun_memory_extension(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o00200: 0o6211, // CDF 1
+		0o00201: 0o1610, // TAD I 210
+		0o00202: 0o6212, // CIF 1
+		0o00203: 0o5204, // JMP 204
+		0o00210: 0o0300,
+		0o10300: 0o1200,
+		0o10204: 0o6214, // RDF
+		0o10205: 0o6224, // RIF
+		0o10206: 0o7402, // HLT
+	}
+
+	p, err := New(WithMemorySize(8192))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+
+	stop, err := p.RunUntil(Halted(), CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := stop.State
+	if !stop.Halted || s.PC-1 != 0o206 || s.IF != 1 || s.DF != 1 {
+		t.Fatalf("HLT - got PC: %04o, IF: %o, DF: %o, want PC: 0206, IF: 1, DF: 1",
+			s.PC-1, s.IF, s.DF)
+	}
+	if s.AC != 0o1210 {
+		t.Errorf("got AC: %04o, want: 1210", s.AC)
+	}
+}
+
+// An interrupt saves the fields and goes to field 0
+func TestRun_memory_extension_interrupt(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o00001: 0o6234, // RIB
+		0o00002: 0o7402, // HLT
+		0o00200: 0o6221, // CDF 2
+		0o00201: 0o6212, // CIF 1
+		0o00202: 0o6001, // ION
+		0o00203: 0o6046, // TLS
+		0o00204: 0o5205, // JMP 205
+		0o10205: 0o5205, // JMP 205
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithMemorySize(3*4096), WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+
+	stop, err := p.RunUntil(Halted(), CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := stop.State
+	if !stop.Halted || s.PC-1 != 0o2 || s.IF != 0 || s.DF != 0 {
+		t.Fatalf("HLT - got PC: %04o, IF: %o, DF: %o, want PC: 0002, IF: 0, DF: 0",
+			s.PC-1, s.IF, s.DF)
+	}
+	// The interrupt is inhibited after CIF until JMP
+	if s.AC != 0o12 || p.mem[0] != 0o205 {
+		t.Errorf("got AC: %04o, mem[0]: %04o, want AC: 0012, mem[0]: 0205",
+			s.AC, p.mem[0])
+	}
+}
+
+func TestRun_PDP8E_MQ_instructions(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o1210, // TAD 210
+		0o201: 0o7421, // MQL
+		0o202: 0o1211, // TAD 211
+		0o203: 0o7521, // SWP
+		0o204: 0o7501, // MQA
+		0o205: 0o7002, // BSW
+		0o206: 0o7402, // HLT
+		0o210: 0o0017,
+		0o211: 0o1200,
+	}
+
+	p, err := New(WithModel(ModelPDP8E))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+
+	stop, err := p.RunUntil(Halted(), CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.State.AC != 0o1712 || stop.State.MQ != 0o1200 {
+		t.Errorf("got AC: %04o, MQ: %04o, want AC: 1712, MQ: 1200",
+			stop.State.AC, stop.State.MQ)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Encoding of profiles in pprof's protocol buffer format
+ *
+ * Only the parts of profile.proto needed by Profiler are encoded.
+ * See: https://github.com/google/pprof/blob/main/proto/profile.proto
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Field numbers from profile.proto
+const (
+	pprofProfileSampleType   = 1
+	pprofProfileSample       = 2
+	pprofProfileMapping      = 3
+	pprofProfileLocation     = 4
+	pprofProfileFunction     = 5
+	pprofProfileStringTable  = 6
+	pprofProfilePeriodType   = 11
+	pprofProfilePeriod       = 12
+	pprofValueTypeType       = 1
+	pprofValueTypeUnit       = 2
+	pprofSampleLocationID    = 1
+	pprofSampleValue         = 2
+	pprofMappingID           = 1
+	pprofMappingMemoryStart  = 2
+	pprofMappingMemoryLimit  = 3
+	pprofMappingFilename     = 5
+	pprofMappingHasFunctions = 7
+	pprofMappingHasFilenames = 8
+	pprofMappingHasLines     = 9
+	pprofLocationID          = 1
+	pprofLocationMappingID   = 2
+	pprofLocationAddress     = 3
+	pprofLocationLine        = 4
+	pprofLineFunctionID      = 1
+	pprofLineLine            = 2
+	pprofFunctionID          = 1
+	pprofFunctionName        = 2
+	pprofFunctionSystemName  = 3
+	pprofFunctionFilename    = 4
+	pprofFunctionStartLine   = 5
+)
+
+// protoBuf builds a protocol buffer message
+type protoBuf struct {
+	b []byte
+}
+
+func (pb *protoBuf) varint(v uint64) {
+	for v >= 0x80 {
+		pb.b = append(pb.b, byte(v)|0x80)
+		v >>= 7
+	}
+	pb.b = append(pb.b, byte(v))
+}
+
+func (pb *protoBuf) key(field int, wireType int) {
+	pb.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (pb *protoBuf) uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	pb.key(field, 0)
+	pb.varint(v)
+}
+
+func (pb *protoBuf) int64(field int, v int64) {
+	pb.uint64(field, uint64(v))
+}
+
+func (pb *protoBuf) bool(field int, v bool) {
+	if v {
+		pb.uint64(field, 1)
+	}
+}
+
+func (pb *protoBuf) bytes(field int, b []byte) {
+	pb.key(field, 2)
+	pb.varint(uint64(len(b)))
+	pb.b = append(pb.b, b...)
+}
+
+func (pb *protoBuf) string(field int, s string) {
+	pb.bytes(field, []byte(s))
+}
+
+func (pb *protoBuf) message(field int, m *protoBuf) {
+	pb.bytes(field, m.b)
+}
+
+// packed encodes a repeated integer field
+func (pb *protoBuf) packed(field int, vs []uint64) {
+	var m protoBuf
+	for _, v := range vs {
+		m.varint(v)
+	}
+	pb.message(field, &m)
+}
+
+// pprofStrings is the string table of a profile, the
+// first string must be empty
+type pprofStrings struct {
+	strings []string
+	index   map[string]int64
+}
+
+func newPprofStrings() *pprofStrings {
+	return &pprofStrings{strings: []string{""}, index: map[string]int64{"": 0}}
+}
+
+func (st *pprofStrings) id(s string) int64 {
+	if i, ok := st.index[s]; ok {
+		return i
+	}
+	i := int64(len(st.strings))
+	st.strings = append(st.strings, s)
+	st.index[s] = i
+	return i
+}
+
+func pprofValueType(st *pprofStrings, typ string, unit string) *protoBuf {
+	var m protoBuf
+	m.int64(pprofValueTypeType, st.id(typ))
+	m.int64(pprofValueTypeUnit, st.id(unit))
+	return &m
+}
+
+// writeGzipped writes the profile gzipped as expected by pprof
+func writeGzipped(w io.Writer, pb *protoBuf) error {
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(pb.b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,262 @@
+/*
+ * A profiler for programs running on the emulator
+ *
+ * This counts every instruction executed against its address and the
+ * subroutine it was executed in.  Subroutines are found in the same
+ * way as for CallStack.  The profile can be written in pprof format
+ * so that 'go tool pprof' can be used to examine it.  For annotated
+ * listings, the functions in the profile refer to a listing written by
+ * WriteListing in which the line number is one more than the address.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Names of the instruction classes in the instruction mix
+var mixNames = [...]string{
+	"AND", "TAD", "ISZ", "DCA", "JMS", "JMP", "IOT",
+	"OPR group 1", "OPR group 2", "OPR group 3",
+}
+
+// Profiler is an Observer which counts the instructions executed.
+// It is attached with AddObserver.
+type Profiler struct {
+	NopObserver
+	// The name of the listing file used by annotated listings,
+	// defaults to pdp8.lst
+	Filename string
+
+	p     *PDP8
+	root  *profNode
+	cur   *profNode // The subroutine being executed
+	depth int
+	mix   [len(mixNames)]int64
+}
+
+// profNode is a subroutine in the call tree
+type profNode struct {
+	entry    uint // The address of the subroutine, where JMS stores the return address
+	callPC   uint // The address of the JMS that called it
+	parent   *profNode
+	children map[[2]uint]*profNode // Keyed by callPC and entry
+	cycles   map[uint]int64        // The instructions executed at each address
+}
+
+func newProfNode(parent *profNode, callPC uint, entry uint) *profNode {
+	return &profNode{
+		entry:    entry,
+		callPC:   callPC,
+		parent:   parent,
+		children: map[[2]uint]*profNode{},
+		cycles:   map[uint]int64{},
+	}
+}
+
+// NewProfiler returns a Profiler for p
+func NewProfiler(p *PDP8) *Profiler {
+	root := newProfNode(nil, 0, noEntry)
+	return &Profiler{Filename: "pdp8.lst", p: p, root: root, cur: root}
+}
+
+func (pr *Profiler) InstructionExecuted(pc uint, ir uint, ea uint) {
+	pr.cur.cycles[pc]++
+
+	opCode := ir >> 9
+	switch {
+	case opCode < 6:
+		pr.mix[opCode]++
+	case opCode == 6:
+		pr.mix[6]++
+	case (ir & 0o400) == 0:
+		pr.mix[7]++
+	case (ir & 0o1) == 0:
+		pr.mix[8]++
+	default:
+		pr.mix[9]++
+	}
+
+	if opCode == 4 { // JMS
+		pr.call(pc, ea)
+	} else if entry, ok := returnEntry(pc, ir); ok {
+		pr.ret(entry)
+	}
+}
+
+func (pr *Profiler) InterruptTaken(pc uint) {
+	pr.call(pc, 0)
+}
+
+// call enters the subroutine at entry
+func (pr *Profiler) call(callPC uint, entry uint) {
+	if pr.depth >= maxCallDepth {
+		return
+	}
+	key := [2]uint{callPC, entry}
+	n, ok := pr.cur.children[key]
+	if !ok {
+		n = newProfNode(pr.cur, callPC, entry)
+		pr.cur.children[key] = n
+	}
+	pr.cur = n
+	pr.depth++
+}
+
+// ret returns from the subroutine at entry if it is on the call stack
+func (pr *Profiler) ret(entry uint) {
+	depth := pr.depth
+	for n := pr.cur; n.parent != nil; n = n.parent {
+		depth--
+		if n.entry == entry {
+			pr.cur = n.parent
+			pr.depth = depth
+			return
+		}
+	}
+}
+
+// Mix returns the number of instructions executed of each class
+func (pr *Profiler) Mix() map[string]int64 {
+	m := make(map[string]int64, len(mixNames))
+	for i, name := range mixNames {
+		m[name] = pr.mix[i]
+	}
+	return m
+}
+
+// WriteMix writes a summary of the instruction mix
+func (pr *Profiler) WriteMix(w io.Writer) error {
+	var total int64
+	for _, n := range pr.mix {
+		total += n
+	}
+	bw := bufio.NewWriter(w)
+	for i, name := range mixNames {
+		percent := 0.0
+		if total > 0 {
+			percent = float64(pr.mix[i]) * 100 / float64(total)
+		}
+		fmt.Fprintf(bw, "%-12s %12d %6.2f%%\n", name, pr.mix[i], percent)
+	}
+	fmt.Fprintf(bw, "%-12s %12d\n", "Total", total)
+	return bw.Flush()
+}
+
+// WriteListing writes a disassembly of memory to use with annotated
+// listings from pprof.  Line n is address n-1.
+func (pr *Profiler) WriteListing(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for addr, v := range pr.p.mem {
+		fmt.Fprintf(bw, "%05o  %04o  %s\n", addr, v, pr.p.Disassemble(uint(addr), v))
+	}
+	return bw.Flush()
+}
+
+// WriteProfile writes the profile in pprof's gzipped protocol
+// buffer format
+func (pr *Profiler) WriteProfile(w io.Writer) error {
+	var prof protoBuf
+	st := newPprofStrings()
+
+	prof.message(pprofProfileSampleType, pprofValueType(st, "instructions", "count"))
+	prof.message(pprofProfilePeriodType, pprofValueType(st, "instructions", "count"))
+	prof.int64(pprofProfilePeriod, 1)
+
+	var mapping protoBuf
+	mapping.uint64(pprofMappingID, 1)
+	mapping.uint64(pprofMappingMemoryStart, 0)
+	mapping.uint64(pprofMappingMemoryLimit, uint64(len(pr.p.mem)))
+	mapping.int64(pprofMappingFilename, st.id("pdp8"))
+	mapping.bool(pprofMappingHasFunctions, true)
+	mapping.bool(pprofMappingHasFilenames, true)
+	mapping.bool(pprofMappingHasLines, true)
+	prof.message(pprofProfileMapping, &mapping)
+
+	filename := st.id(pr.Filename)
+	funcIDs := map[uint]uint64{}
+	funcID := func(entry uint) uint64 {
+		if id, ok := funcIDs[entry]; ok {
+			return id
+		}
+		id := uint64(len(funcIDs) + 1)
+		funcIDs[entry] = id
+		name := subroutineName(entry)
+		startLine := int64(0)
+		if entry != noEntry {
+			startLine = int64(entry) + 1
+		}
+		var f protoBuf
+		f.uint64(pprofFunctionID, id)
+		f.int64(pprofFunctionName, st.id(name))
+		f.int64(pprofFunctionSystemName, st.id(name))
+		f.int64(pprofFunctionFilename, filename)
+		f.int64(pprofFunctionStartLine, startLine)
+		prof.message(pprofProfileFunction, &f)
+		return id
+	}
+
+	locIDs := map[[2]uint]uint64{}
+	locID := func(entry uint, pc uint) uint64 {
+		key := [2]uint{entry, pc}
+		if id, ok := locIDs[key]; ok {
+			return id
+		}
+		id := uint64(len(locIDs) + 1)
+		locIDs[key] = id
+		var line protoBuf
+		line.uint64(pprofLineFunctionID, funcID(entry))
+		line.int64(pprofLineLine, int64(pc)+1)
+		var l protoBuf
+		l.uint64(pprofLocationID, id)
+		l.uint64(pprofLocationMappingID, 1)
+		l.uint64(pprofLocationAddress, uint64(pc))
+		l.message(pprofLocationLine, &line)
+		prof.message(pprofProfileLocation, &l)
+		return id
+	}
+
+	var walk func(n *profNode)
+	walk = func(n *profNode) {
+		pcs := make([]uint, 0, len(n.cycles))
+		for pc := range n.cycles {
+			pcs = append(pcs, pc)
+		}
+		sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+		for _, pc := range pcs {
+			stack := []uint64{locID(n.entry, pc)}
+			for c := n; c.parent != nil; c = c.parent {
+				stack = append(stack, locID(c.parent.entry, c.callPC))
+			}
+			var s protoBuf
+			s.packed(pprofSampleLocationID, stack)
+			s.packed(pprofSampleValue, []uint64{uint64(n.cycles[pc])})
+			prof.message(pprofProfileSample, &s)
+		}
+		keys := make([][2]uint, 0, len(n.children))
+		for k := range n.children {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i][0] < keys[j][0] ||
+				(keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+		})
+		for _, k := range keys {
+			walk(n.children[k])
+		}
+	}
+	walk(pr.root)
+
+	for _, s := range st.strings {
+		prof.string(pprofProfileStringTable, s)
+	}
+	return writeGzipped(w, &prof)
+}
@@ -0,0 +1,83 @@
+package pdp8
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o4210, // JMS 210
+		0o201: 0o4210, // JMS 210
+		0o202: 0o7402, // HLT
+		0o210: 0o0000, // Return address
+		0o211: 0o7001, // IAC
+		0o212: 0o5610, // JMP I 210
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	pr := NewProfiler(p)
+	p.AddObserver(pr)
+
+	hlt, _, err := p.Run(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hlt {
+		t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
+	}
+
+	if pr.cur != pr.root {
+		t.Errorf("profiler didn't return from subroutine")
+	}
+	sub := pr.root.children[[2]uint{0o200, 0o210}]
+	if sub == nil || sub.cycles[0o211] != 1 || sub.cycles[0o212] != 1 {
+		t.Fatalf("subroutine called from 0200 not counted correctly: %+v", sub)
+	}
+	if pr.root.cycles[0o200] != 1 || pr.root.cycles[0o202] != 1 {
+		t.Errorf("main not counted correctly: %v", pr.root.cycles)
+	}
+
+	mix := pr.Mix()
+	if mix["JMS"] != 2 || mix["JMP"] != 2 || mix["OPR group 1"] != 2 ||
+		mix["OPR group 2"] != 1 {
+		t.Errorf("got mix: %v", mix)
+	}
+
+	out := &bytes.Buffer{}
+	if err := pr.WriteMix(out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "JMS                     2  28.57%") {
+		t.Errorf("got mix summary:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := pr.WriteProfile(out); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"instructions", "main", "sub_00210", "pdp8.lst"} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("profile doesn't contain: %q", s)
+		}
+	}
+}
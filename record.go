@@ -0,0 +1,262 @@
+/*
+ * Record and replay of external input
+ *
+ * Input from a console arrives at whatever instruction it happens to
+ * land on and so a session can't normally be repeated.  A Recorder
+ * logs each piece of external input with the cycle it was consumed at
+ * and a Replayer feeds it back at exactly the same point.  Providing
+ * the machine starts in the same state, a replayed session will
+ * reproduce the original one exactly.
+ *
+ * Each input is recorded as a line of text:
+ *   cycle stream kind call data
+ * where kind is read, eof, err or event, call is the number of the
+ * Read call on that stream, starting at 1, and data is in hex.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	recordRead  = "read"
+	recordEOF   = "eof"
+	recordErr   = "err"
+	recordEvent = "event"
+)
+
+type recordEntry struct {
+	cycle    uint64
+	stream   string
+	kind     string
+	call     int
+	data     []byte
+	replayed bool // Whether the entry has been replayed
+}
+
+// Recorder logs external input to a machine so that it can be replayed
+type Recorder struct {
+	p   *PDP8
+	w   io.Writer
+	err error
+}
+
+// NewRecorder returns a Recorder that logs input to p on w
+func NewRecorder(p *PDP8, w io.Writer) *Recorder {
+	return &Recorder{p: p, w: w}
+}
+
+// Reader returns a reader which records everything read from in on
+// stream.  It should be passed to a device in place of in.
+func (r *Recorder) Reader(stream string, in io.Reader) io.Reader {
+	return &recordingReader{r: r, stream: stream, in: in}
+}
+
+// Event records data on stream at the current cycle.  This is for
+// input given between calls to Run such as changing the switch
+// register or starting the paper tape reader.
+func (r *Recorder) Event(stream string, data []byte) {
+	r.write(recordEntry{
+		cycle:  r.p.Cycles(),
+		stream: stream,
+		kind:   recordEvent,
+		data:   data,
+	})
+}
+
+// Err returns the first error that occurred while writing the log
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+func (r *Recorder) write(e recordEntry) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = fmt.Fprintf(r.w, "%d %q %s %d %x\n",
+		e.cycle, e.stream, e.kind, e.call, e.data)
+}
+
+type recordingReader struct {
+	r      *Recorder
+	stream string
+	in     io.Reader
+	call   int
+}
+
+func (rr *recordingReader) Read(b []byte) (int, error) {
+	n, err := rr.in.Read(b)
+	rr.call++
+	e := recordEntry{
+		cycle:  rr.r.p.Cycles(),
+		stream: rr.stream,
+		call:   rr.call,
+	}
+	switch {
+	case n > 0:
+		e.kind = recordRead
+		e.data = b[:n]
+		rr.r.write(e)
+		// Any error will be returned again by the next Read
+		return n, nil
+	case err == io.EOF:
+		e.kind = recordEOF
+		rr.r.write(e)
+	case err != nil:
+		e.kind = recordErr
+		e.data = []byte(err.Error())
+		rr.r.write(e)
+	}
+	return n, err
+}
+
+// Replayer feeds input logged by a Recorder back to a machine
+type Replayer struct {
+	p       *PDP8
+	entries []*recordEntry // Every entry in the order logged
+	reads   map[string][]*recordEntry
+	events  map[string][]*recordEntry
+	pending int // The number of entries not yet replayed
+}
+
+// NewReplayer returns a Replayer that feeds the log read from r to p
+func NewReplayer(p *PDP8, r io.Reader) (*Replayer, error) {
+	rp := &Replayer{
+		p:      p,
+		reads:  map[string][]*recordEntry{},
+		events: map[string][]*recordEntry{},
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		e := &recordEntry{}
+		var data string
+		_, err := fmt.Sscanf(scanner.Text(), "%d %q %s %d %s",
+			&e.cycle, &e.stream, &e.kind, &e.call, &data)
+		if err != nil {
+			// An empty data field isn't written
+			_, err = fmt.Sscanf(scanner.Text(), "%d %q %s %d",
+				&e.cycle, &e.stream, &e.kind, &e.call)
+		}
+		if err == nil && data != "" {
+			_, err = fmt.Sscanf(data, "%x", &e.data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay log line %d: %w", line, err)
+		}
+		switch e.kind {
+		case recordRead, recordEOF, recordErr:
+			rp.reads[e.stream] = append(rp.reads[e.stream], e)
+		case recordEvent:
+			rp.events[e.stream] = append(rp.events[e.stream], e)
+		default:
+			return nil, fmt.Errorf("replay log line %d: unknown kind: %s",
+				line, e.kind)
+		}
+		rp.entries = append(rp.entries, e)
+		rp.pending++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// Reader returns a reader which replays what was recorded on stream.
+// It should be passed to a device in place of the original reader.
+// If the machine reads at a different cycle to the one recorded a
+// *ReplayError is returned.
+func (rp *Replayer) Reader(stream string) io.Reader {
+	return &replayingReader{rp: rp, stream: stream}
+}
+
+// Handle arranges for fn to be called with the data of each event
+// recorded on stream at the cycle it was recorded.  Events recorded
+// at a cycle that has already passed are handled immediately.
+func (rp *Replayer) Handle(stream string, fn func(data []byte) error) error {
+	entries := rp.events[stream]
+	delete(rp.events, stream)
+	for _, e := range entries {
+		e := e
+		run := func() error {
+			e.replayed = true
+			rp.pending--
+			return fn(e.data)
+		}
+		if e.cycle <= rp.p.Cycles() {
+			if err := run(); err != nil {
+				return err
+			}
+			continue
+		}
+		rp.p.schedule(rp, e.cycle, run)
+	}
+	return nil
+}
+
+// Done returns whether all the recorded input has been replayed
+func (rp *Replayer) Done() bool {
+	return rp.pending == 0
+}
+
+// Finish returns a *ReplayError if any of the recorded input hasn't
+// been replayed.  It should be called once the replayed session has
+// ended, as a session that makes fewer reads than the one recorded
+// wouldn't otherwise be noticed.
+func (rp *Replayer) Finish() error {
+	if rp.pending == 0 {
+		return nil
+	}
+	for _, e := range rp.entries {
+		if !e.replayed {
+			return &ReplayError{
+				Stream:     e.stream,
+				Cycle:      rp.p.Cycles(),
+				Want:       e.cycle,
+				Unconsumed: rp.pending,
+			}
+		}
+	}
+	return nil
+}
+
+type replayingReader struct {
+	rp     *Replayer
+	stream string
+	call   int
+}
+
+func (rr *replayingReader) Read(b []byte) (int, error) {
+	rr.call++
+	entries := rr.rp.reads[rr.stream]
+	if len(entries) == 0 || entries[0].call != rr.call {
+		return 0, nil
+	}
+	e := entries[0]
+	rr.rp.reads[rr.stream] = entries[1:]
+	e.replayed = true
+	rr.rp.pending--
+
+	if cycle := rr.rp.p.Cycles(); cycle != e.cycle {
+		return 0, &ReplayError{Stream: rr.stream, Cycle: cycle, Want: e.cycle}
+	}
+	switch e.kind {
+	case recordEOF:
+		return 0, io.EOF
+	case recordErr:
+		return 0, errors.New(string(e.data))
+	}
+	if len(e.data) > len(b) {
+		return 0, fmt.Errorf("replay: stream %q: recorded %d bytes, buffer has room for %d",
+			rr.stream, len(e.data), len(b))
+	}
+	return copy(b, e.data), nil
+}
@@ -0,0 +1,190 @@
+package pdp8
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// Returns a byte from s every n calls to Read, like a slow typist
+type slowReader struct {
+	s     []byte
+	n     int
+	calls int
+}
+
+func (r *slowReader) Read(b []byte) (int, error) {
+	r.calls++
+	if r.calls%r.n != 0 {
+		return 0, nil
+	}
+	if len(r.s) == 0 {
+		return 0, io.EOF
+	}
+	b[0] = r.s[0]
+	r.s = r.s[1:]
+	return 1, nil
+}
+
+var echoRoutine = map[uint]uint{
+	0o200: 0o6031, // KSF
+	0o201: 0o5200, // JMP 200
+	0o202: 0o6036, // KRB
+	0o203: 0o6046, // TLS
+	0o204: 0o6041, // TSF
+	0o205: 0o5204, // JMP 204
+	0o206: 0o5200, // JMP 200
+}
+
+func newEchoMachine(t *testing.T) *PDP8 {
+	t.Helper()
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range echoRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+	return p
+}
+
+func TestRecordReplay(t *testing.T) {
+	log := &bytes.Buffer{}
+
+	// Record
+	p := newEchoMachine(t)
+	r := NewRecorder(p, log)
+	ttyOut := &bytes.Buffer{}
+	tty := NewTTY(r.Reader("tty", &slowReader{s: []byte("hello"), n: 7}), ttyOut)
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.Run(50); err != nil {
+		t.Fatal(err)
+	}
+	p.SetSR(0o1234)
+	r.Event("sr", []byte(strconv.FormatUint(0o1234, 8)))
+	if _, err := p.RunUntil(OutputContains(ttyOut, []byte("hello")), CycleBudget(5000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	wantState := p.State()
+	wantCycles := p.Cycles()
+
+	// Replay
+	p = newEchoMachine(t)
+	rp, err := NewReplayer(p, bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttyOut = &bytes.Buffer{}
+	tty = NewTTY(rp.Reader("tty"), ttyOut)
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	err = rp.Handle("sr", func(data []byte) error {
+		sr, err := strconv.ParseUint(string(data), 8, 12)
+		p.SetSR(uint(sr))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.Run(int(wantCycles)); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttyOut.String() != "hello" {
+		t.Errorf("got output: %q, want: \"hello\"", ttyOut.String())
+	}
+	if got := p.State(); got != wantState {
+		t.Errorf("got state: %+v, want: %+v", got, wantState)
+	}
+	if !rp.Done() {
+		t.Error("replay not done")
+	}
+	if err := rp.Finish(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReplay_unconsumed(t *testing.T) {
+	log := &bytes.Buffer{}
+
+	p := newEchoMachine(t)
+	r := NewRecorder(p, log)
+	ttyOut := &bytes.Buffer{}
+	tty := NewTTY(r.Reader("tty", &slowReader{s: []byte("hi"), n: 5}), ttyOut)
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RunUntil(OutputContains(ttyOut, []byte("hi")), CycleBudget(5000)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay fewer cycles than recorded so that not every read is made
+	p = newEchoMachine(t)
+	rp, err := NewReplayer(p, bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tty = NewTTY(rp.Reader("tty"), &bytes.Buffer{})
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.Run(3); err != nil {
+		t.Fatal(err)
+	}
+
+	err = rp.Finish()
+	var re *ReplayError
+	if !errors.As(err, &re) {
+		t.Fatalf("got error: %v, want: *ReplayError", err)
+	}
+	if re.Stream != "tty" || re.Unconsumed == 0 || re.Want <= re.Cycle {
+		t.Errorf("got: %+v, want stream: tty, inputs unconsumed and recorded after the end", re)
+	}
+}
+
+func TestReplay_diverged(t *testing.T) {
+	log := &bytes.Buffer{}
+
+	p := newEchoMachine(t)
+	r := NewRecorder(p, log)
+	ttyOut := &bytes.Buffer{}
+	tty := NewTTY(r.Reader("tty", &slowReader{s: []byte("hi"), n: 5}), ttyOut)
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RunUntil(OutputContains(ttyOut, []byte("hi")), CycleBudget(5000)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay with an extra instruction so that the timing differs
+	p = newEchoMachine(t)
+	p.mem[0o206] = 0o5207 // JMP 207
+	p.mem[0o207] = 0o7000 // NOP
+	p.mem[0o210] = 0o5200 // JMP 200
+	rp, err := NewReplayer(p, bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tty = NewTTY(rp.Reader("tty"), &bytes.Buffer{})
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = p.Run(5000)
+	var re *ReplayError
+	if !errors.As(err, &re) {
+		t.Fatalf("got error: %v, want: *ReplayError", err)
+	}
+	if re.Stream != "tty" || re.Cycle == re.Want {
+		t.Errorf("got: %+v, want stream: tty and cycles to differ", re)
+	}
+}
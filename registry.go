@@ -0,0 +1,200 @@
+/*
+ * The registry of devices attached to a machine
+ *
+ * IOTs are routed to devices through a table indexed by device
+ * number.  Devices can be attached at device numbers other than the
+ * ones they expect, in which case the device field of each IOT is
+ * altered to the device number expected by the device.  Devices can
+ * be removed or replaced between calls to Run.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+)
+
+// attachedDevice is a device and the device numbers it is attached at
+type attachedDevice struct {
+	d Device
+	// The device numbers the device is attached at and the device
+	// numbers the device expects, in the same order
+	numbers []int
+	native  []int
+}
+
+// deviceSlot is an entry in the table of device numbers
+type deviceSlot struct {
+	a      *attachedDevice
+	native uint // The device number expected by the device
+}
+
+// AttachedDevice describes a device attached to a machine
+type AttachedDevice struct {
+	Device  Device
+	Numbers []int // The device numbers it is attached at
+}
+
+// AddDevice attaches d at the device numbers it expects
+func (p *PDP8) AddDevice(d Device) error {
+	return p.addDevice(d, nil)
+}
+
+// RemoveDevice detaches d and drops any events it has scheduled.  d
+// isn't closed.
+func (p *PDP8) RemoveDevice(d Device) error {
+	i := p.findDevice(d)
+	if i < 0 {
+		return errors.New("device not attached")
+	}
+	for _, n := range p.devices[i].numbers {
+		p.iotDevices[n] = deviceSlot{}
+	}
+	p.devices = append(p.devices[:i], p.devices[i+1:]...)
+	p.cancelEvents(d)
+	attach(d, nil)
+	return nil
+}
+
+// ReplaceDevice detaches from and attaches to at the same device
+// numbers.  to must use as many device numbers as from.  Any events
+// scheduled by from are dropped.  from isn't closed.
+func (p *PDP8) ReplaceDevice(from Device, to Device) error {
+	if to == nil {
+		return errors.New("device is nil")
+	}
+	i := p.findDevice(from)
+	if i < 0 {
+		return errors.New("device not attached")
+	}
+	if p.findDevice(to) >= 0 {
+		return errors.New("device already attached")
+	}
+	numbers := p.devices[i].numbers
+	native := to.DeviceNumbers()
+	if len(native) != len(numbers) {
+		return fmt.Errorf("device needs %d device numbers, got: %d",
+			len(native), len(numbers))
+	}
+	a := &attachedDevice{d: to, numbers: numbers, native: native}
+	p.devices[i] = a
+	for j, n := range numbers {
+		p.iotDevices[n] = deviceSlot{a: a, native: uint(native[j])}
+	}
+	p.cancelEvents(from)
+	attach(from, nil)
+	attach(to, p)
+	return nil
+}
+
+// Reset clears AC, L, MQ and the interrupt system and resets every
+// device.  Memory and the other registers are unaltered.
+func (p *PDP8) Reset() {
+	p.caf()
+	p.mq = 0
+	for _, a := range p.devices {
+		a.d.Reset()
+	}
+}
+
+// Devices returns the attached devices in the order they were attached
+func (p *PDP8) Devices() []AttachedDevice {
+	r := make([]AttachedDevice, len(p.devices))
+	for i, a := range p.devices {
+		r[i] = AttachedDevice{Device: a.d, Numbers: append([]int{}, a.numbers...)}
+	}
+	return r
+}
+
+// addDevice attaches d at the device numbers passed or if none are
+// passed, at the device numbers it expects.  The device numbers are
+// all checked before anything is altered.
+func (p *PDP8) addDevice(d Device, numbers []int) error {
+	if d == nil {
+		return errors.New("device is nil")
+	}
+	native := d.DeviceNumbers()
+	if len(native) == 0 {
+		return errors.New("device has no device numbers")
+	}
+	if len(numbers) == 0 {
+		numbers = native
+	} else if len(numbers) != len(native) {
+		return fmt.Errorf("device needs %d device numbers, got: %d",
+			len(native), len(numbers))
+	}
+	if p.findDevice(d) >= 0 {
+		return errors.New("device already attached")
+	}
+	for i, n1 := range numbers {
+		if err := p.checkDeviceNumber(n1); err != nil {
+			return err
+		}
+		for _, n2 := range numbers[:i] {
+			if n1 == n2 {
+				return fmt.Errorf("device number conflict: %02o", n1)
+			}
+		}
+		if p.iotDevices[n1].a != nil {
+			return fmt.Errorf("device number conflict: %02o", n1)
+		}
+	}
+	a := &attachedDevice{
+		d:       d,
+		numbers: append([]int{}, numbers...),
+		native:  native,
+	}
+	p.devices = append(p.devices, a)
+	for i, n := range numbers {
+		p.iotDevices[n] = deviceSlot{a: a, native: uint(native[i])}
+	}
+	attach(d, p)
+	return nil
+}
+
+// attach tells d the machine it is attached to if it is an Attacher
+func attach(d Device, p *PDP8) {
+	if at, ok := d.(Attacher); ok {
+		at.Attach(p)
+	}
+}
+
+// findDevice returns the index of d in p.devices or -1 if not attached
+func (p *PDP8) findDevice(d Device) int {
+	for i, a := range p.devices {
+		if a.d == d {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkDeviceNumber returns an error if n can't be used by a device
+func (p *PDP8) checkDeviceNumber(n int) error {
+	if n <= 0 || n > 0o77 {
+		return fmt.Errorf("invalid device number: %02o", n)
+	}
+	if len(p.mem) > fieldSize && n >= 0o20 && n <= 0o27 {
+		return fmt.Errorf("device number used by memory extension: %02o", n)
+	}
+	return nil
+}
+
+// deviceIOT passes the IOT instruction ir to the device attached at
+// device, if any, with the device field altered to the device number
+// expected by the device
+func (p *PDP8) deviceIOT(device uint, ir uint) error {
+	var err error
+	slot := p.iotDevices[device]
+	if slot.a == nil {
+		return nil
+	}
+	ir = (ir &^ 0o770) | slot.native<<3
+	p.pc, p.lac, err = slot.a.d.IOT(ir, p.pc, p.lac)
+	return err
+}
@@ -0,0 +1,220 @@
+package pdp8
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDevices(t *testing.T) {
+	rw := newDummyReadWriter()
+	tty1 := NewTTY(rw, rw)
+	tty2 := NewTTY(rw, rw)
+	defer tty1.Close()
+	defer tty2.Close()
+
+	p, err := New(WithDevice(tty1), WithDevice(tty2, 0o40, 0o41))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AttachedDevice{
+		{Device: tty1, Numbers: []int{0o3, 0o4}},
+		{Device: tty2, Numbers: []int{0o40, 0o41}},
+	}
+	if got := p.Devices(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+	if info := p.Devices()[1].Device.Info(); info.Name != "TTY" {
+		t.Errorf("got name: %s, want: TTY", info.Name)
+	}
+}
+
+func TestRun_CAF(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6046, // TLS
+		0o201: 0o6001, // ION
+		0o202: 0o7041, // CIA
+		0o203: 0o6007, // CAF
+		0o204: 0o7402, // HLT
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	for _, m := range []Model{ModelPDP8I, ModelPDP8E} {
+		p, err := New(WithModel(m), WithDevice(tty))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for addr, v := range testRoutine {
+			p.mem[addr] = v
+		}
+		p.pc = 0o200
+		// Make sure that the TLS doesn't cause an interrupt
+		p.intInhibit = true
+
+		if _, err := p.RunUntil(Halted(), CycleBudget(500)); err != nil {
+			t.Fatal(err)
+		}
+		cleared := p.lac == 0 && !p.ien && !tty.ttoReadyFlag
+		if cleared != (m == ModelPDP8E) {
+			t.Errorf("%s - got LAC: %05o, ION: %t, TTO flag: %t",
+				m, p.lac, p.ien, tty.ttoReadyFlag)
+		}
+	}
+}
+
+func TestRemoveDevice(t *testing.T) {
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RemoveDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.Devices()); n != 0 {
+		t.Errorf("got %d devices, want: 0", n)
+	}
+	if err := p.RemoveDevice(tty); err == nil {
+		t.Error("RemoveDevice: no error for a device not attached")
+	}
+
+	// The IOT is now ignored
+	p.mem[0o200] = 0o6046 // TLS
+	p.lac = 0o101
+	if _, _, err := p.Run(1); err != nil {
+		t.Fatal(err)
+	}
+	if tty.ttoReadyFlag {
+		t.Error("IOT passed to removed device")
+	}
+
+	// The device numbers can be used again
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddDevice(tty); err == nil {
+		t.Error("AddDevice: no error for a device already attached")
+	}
+}
+
+func TestRemoveDevice_events(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rk05")
+	rk := NewRK8E()
+	defer rk.Close()
+	if err := rk.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Start a read and remove the controller before it finishes
+	p := newDeviceMachine(t, rk, rkRoutine(0o0000, 0), nil)
+	if _, _, err := p.Run(6); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.events) != 1 {
+		t.Fatalf("got %d events, want: 1", len(p.events))
+	}
+	if err := p.RemoveDevice(rk); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.events) != 0 {
+		t.Errorf("got %d events after RemoveDevice, want: 0", len(p.events))
+	}
+	if _, _, err := p.Run(rkBlockCycles); err != nil {
+		t.Fatal(err)
+	}
+	if rk.status != 0 {
+		t.Errorf("got status: %04o, want: 0", rk.status)
+	}
+}
+
+func TestEventCancel(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ran []int
+	e1 := p.schedule(nil, 2, func() error { ran = append(ran, 1); return nil })
+	p.schedule(nil, 2, func() error { ran = append(ran, 2); return nil })
+	e1.cancel()
+	e1.cancel()
+	if _, _, err := p.Run(5); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ran, []int{2}) {
+		t.Errorf("events run got: %v, want: [2]", ran)
+	}
+}
+
+func TestReplaceDevice(t *testing.T) {
+	rw := newDummyReadWriter()
+	out1 := &bytes.Buffer{}
+	out2 := &bytes.Buffer{}
+	tty1 := NewTTY(rw, out1)
+	tty2 := NewTTY(rw, out2)
+	defer tty1.Close()
+	defer tty2.Close()
+
+	p, err := New(WithDevice(tty1, 0o40, 0o41))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ReplaceDevice(tty2, tty1); err == nil {
+		t.Error("ReplaceDevice: no error for a device not attached")
+	}
+	if err := p.ReplaceDevice(tty1, nil); err == nil {
+		t.Error("ReplaceDevice: no error for a nil device")
+	}
+	if err := p.AddDevice(nil); err == nil {
+		t.Error("AddDevice: no error for a nil device")
+	}
+	if err := p.ReplaceDevice(tty1, tty2); err != nil {
+		t.Fatal(err)
+	}
+	want := []AttachedDevice{{Device: tty2, Numbers: []int{0o40, 0o41}}}
+	if got := p.Devices(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	p.mem[0o200] = 0o6416 // TLS at device 41
+	p.lac = 0o101
+	if _, _, err := p.Run(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := out2.String(); got != "A" {
+		t.Errorf("got output: %q, want: %q", got, "A")
+	}
+	if got := out1.String(); got != "" {
+		t.Errorf("got output from replaced device: %q", got)
+	}
+}
+
+func TestReset(t *testing.T) {
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.lac = 0o17777
+	p.mq = 0o1234
+	p.ien = true
+	tty.ttoReadyFlag = true
+	tty.ttiInputBuffer = 0o301
+	p.Reset()
+	if p.lac != 0 || p.mq != 0 || p.ien || p.pc != 0o200 {
+		t.Errorf("got LAC: %05o, MQ: %04o, ION: %t, PC: %04o",
+			p.lac, p.mq, p.ien, p.pc)
+	}
+	if tty.ttoReadyFlag || tty.ttiInputBuffer != 0 {
+		t.Error("TTY not reset")
+	}
+}
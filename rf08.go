@@ -0,0 +1,195 @@
+/*
+ * An RF08 disk controller with up to four RS08 fixed head disks
+ *
+ * The controller is devices 60-62 and 64.  Each disk holds 256K words
+ * in 128 tracks of 2048 words.  The timing and transfers are those of
+ * the fixed head disk shared with the DF32.  Each disk has 16 write
+ * lock switches which each protect 16K words.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+const (
+	rfMaxDisks  = 4
+	rfDiskWords = 262144
+	rfLockWords = 16384 // Words protected by each write lock switch
+)
+
+// Status register
+const (
+	rfStaPhotocell   = 0o4000 // At the photocell mark
+	rfStaWriteLock   = 0o1000 // Write to a write locked address
+	rfStaErrIE       = 0o400  // Interrupt on error
+	rfStaPhotocellIE = 0o200  // Interrupt on photocell
+	rfStaDoneIE      = 0o100  // Interrupt on completion
+	rfStaField       = 0o70   // The memory field
+	rfStaDataLate    = 0o4    // Data late, not emulated
+	rfStaNoDisk      = 0o2    // Address on a disk that doesn't exist
+	rfStaParity      = 0o1    // Parity error, not emulated
+
+	rfStaEnables = rfStaErrIE | rfStaPhotocellIE | rfStaDoneIE
+	rfStaErrors  = rfStaWriteLock | rfStaDataLate | rfStaNoDisk | rfStaParity
+)
+
+type RF08 struct {
+	fixedHeadDisk      // The disk address is 20 bits
+	status        uint // Interrupt enables and memory field
+}
+
+// NewRF08 returns a controller with no image attached
+func NewRF08() *RF08 {
+	rf := &RF08{}
+	rf.fixedHeadDisk = newFixedHeadDisk(rf, "RF08", "disks",
+		rfMaxDisks, rfDiskWords, rfLockWords)
+	return rf
+}
+
+// AttachImage attaches image holding the number of disks passed
+func (rf *RF08) AttachImage(image DiskImage, disks int, writeProtect bool) error {
+	return rf.attachImage(image, disks, writeProtect)
+}
+
+// OpenImage opens the image file filename and attaches it.  If the
+// file doesn't exist it is created unless writeProtect is set.  The
+// file is closed when detached.
+func (rf *RF08) OpenImage(filename string, disks int, writeProtect bool) error {
+	return rf.openImage(filename, disks, writeProtect)
+}
+
+// DetachImage detaches the disk image
+func (rf *RF08) DetachImage() error {
+	return rf.detachImage()
+}
+
+// SetWriteProtect sets write lock switch n of disk
+func (rf *RF08) SetWriteProtect(disk int, n int, on bool) {
+	const perDisk = rfDiskWords / rfLockWords
+	if disk >= 0 && disk < rfMaxDisks && n >= 0 && n < perDisk {
+		rf.writeProtect[disk*perDisk+n] = on
+	}
+}
+
+// Attach also cancels any transfer in progress
+func (rf *RF08) Attach(p *PDP8) {
+	rf.attach(p)
+}
+
+// Closes any image file opened by OpenImage
+func (rf *RF08) Close() error {
+	return rf.DetachImage()
+}
+
+func (rf *RF08) DeviceNumbers() []int {
+	return []int{0o60, 0o61, 0o62, 0o64}
+}
+
+func (rf *RF08) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "RF08",
+		Description: "RS08 fixed head disk controller",
+	}
+}
+
+// Reset clears the controller
+func (rf *RF08) Reset() {
+	rf.CAF()
+}
+
+// CAF clears the registers and flags and cancels any transfer
+func (rf *RF08) CAF() {
+	rf.da = 0
+	rf.status = 0
+	rf.clearFlags()
+}
+
+// Interrupt returns if the completion flag, an error or the photocell
+// is set and the interrupt for it is enabled
+func (rf *RF08) Interrupt() (bool, error) {
+	s := rf.statusReg()
+	return (rf.done && (s&rfStaDoneIE) != 0) ||
+		((s&rfStaErrors) != 0 && (s&rfStaErrIE) != 0) ||
+		((s&rfStaPhotocell) != 0 && (s&rfStaPhotocellIE) != 0), nil
+}
+
+// IOT returns PC, LAC, error
+func (rf *RF08) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	link := lac & 0o10000
+	ac := mask(lac)
+	device := (ir >> 3) & 0o77
+	switch device {
+	case 0o60:
+		if (ir & 0o1) == 0o1 { // DCMA - Clear disk address and flags
+			rf.da &^= 0o7777
+			rf.clearFlags()
+		}
+		if (ir & 0o6) != 0 { // DMAR/DMAW - Load disk address and go
+			rf.da |= ac
+			ac = 0
+			rf.start((ir&0o4) == 0o4, (rf.status&rfStaField)>>3)
+		}
+	case 0o61:
+		switch ir & 0o7 {
+		case 0o1: // DCIM - Clear enables and field
+			rf.status &^= rfStaEnables | rfStaField
+		case 0o2: // DSAC - Skip on address confirmed
+			if rf.position() == rf.da%fhTrackWords {
+				pc = mask(pc + 1)
+			}
+			ac = 0 // Unlike the DF32 the AC is cleared
+		case 0o5: // DIML - Load enables and field
+			rf.status &^= rfStaEnables | rfStaField
+			rf.status |= ac & (rfStaEnables | rfStaField)
+			ac = 0
+		case 0o6: // DIMA - Read status
+			ac = rf.statusReg()
+		}
+	case 0o62:
+		switch ir & 0o7 {
+		case 0o1: // DFSE - Skip on no errors
+			if (rf.statusReg() & rfStaErrors) == 0 {
+				pc = mask(pc + 1)
+			}
+		case 0o2: // DFSC - Skip on completion
+			if rf.done {
+				pc = mask(pc + 1)
+			}
+		case 0o3: // DISK - Skip on error or completion
+			if rf.done || (rf.statusReg()&rfStaErrors) != 0 {
+				pc = mask(pc + 1)
+			}
+		case 0o6: // DMAC - Read disk address
+			ac = rf.da & 0o7777
+		}
+	case 0o64:
+		switch ir & 0o7 {
+		case 0o1: // DCXA - Clear extended disk address
+			rf.da &= 0o7777
+		case 0o3: // DXAL - Load extended disk address
+			rf.da = (ac&0o377)<<12 | rf.da&0o7777
+			ac = 0
+		case 0o5: // DXAC - Read extended disk address
+			ac = rf.da >> 12
+		case 0o6: // DMMT - Maintenance, not emulated
+		}
+	}
+	return pc, link | ac, nil
+}
+
+// statusReg returns the status register
+func (rf *RF08) statusReg() uint {
+	s := rf.status
+	if rf.position() == 0 {
+		s |= rfStaPhotocell
+	}
+	if rf.writeLock {
+		s |= rfStaWriteLock
+	}
+	if rf.noDisk {
+		s |= rfStaNoDisk
+	}
+	return s
+}
@@ -0,0 +1,156 @@
+package pdp8
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// rfRoutine returns a routine which loads the extended disk address
+// with ema, the enables and field with status, starts a transfer
+// with the disk address da using the IOT start, waits for completion
+// and then halts at 0212 if there is an error, otherwise at 0213
+func rfRoutine(ema uint, status uint, da uint, start uint) map[uint]uint {
+	return map[uint]uint{
+		0o200: 0o1220, // TAD 220
+		0o201: 0o6643, // DXAL
+		0o202: 0o1221, // TAD 221
+		0o203: 0o6615, // DIML
+		0o204: 0o1222, // TAD 222
+		0o205: start,
+		0o206: 0o6622, // DFSC
+		0o207: 0o5206, // JMP 206
+		0o210: 0o6621, // DFSE
+		0o211: 0o7402, // HLT
+		0o212: 0o7402, // HLT
+		0o220: ema,
+		0o221: status,
+		0o222: da,
+	}
+}
+
+func TestRF08_write_read(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rf08")
+	rf := NewRF08()
+	defer rf.Close()
+	if err := rf.OpenImage(filename, 2, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write 10 words from field 1 to the second disk
+	da := uint(rfDiskWords + 0o12345)
+	p := newDeviceMachine(t, rf, rfRoutine(da>>12, 0o10, da&0o7777, 0o6605),
+		map[uint]uint{fhWCAddr: 0o7770, fhWCAddr + 1: 0o777})
+	for i := uint(0); i < 0o10; i++ {
+		p.mem[0o11000+i] = 0o5000 + i
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o213 {
+		t.Fatalf("write - got PC: %04o, status: %04o", p.pc, rf.statusReg())
+	}
+	if rf.da != da+0o10 {
+		t.Errorf("got disk address: %07o, want: %07o", rf.da, da+0o10)
+	}
+	words := make([]uint, 0o10)
+	if err := readWords(rf.image, int64(da), words); err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range words {
+		if w != 0o5000+uint(i) {
+			t.Fatalf("disk word %o got: %04o, want: %04o", i, w, 0o5000+i)
+		}
+	}
+
+	// Read them back to field 0
+	p = newDeviceMachine(t, rf, rfRoutine(da>>12, 0, da&0o7777, 0o6603),
+		map[uint]uint{fhWCAddr: 0o7770, fhWCAddr + 1: 0o1777})
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o213 {
+		t.Fatalf("read - got PC: %04o, status: %04o", p.pc, rf.statusReg())
+	}
+	for i := uint(0); i < 0o10; i++ {
+		if got, want := p.mem[0o2000+i], 0o5000+i; got != want {
+			t.Fatalf("mem[%05o] got: %04o, want: %04o", 0o2000+i, got, want)
+		}
+	}
+}
+
+func TestRF08_errors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rf08")
+	cases := []struct {
+		name  string
+		ema   uint
+		start uint
+		want  uint
+	}{
+		{name: "no disk", ema: 0o100, start: 0o6603, want: rfStaNoDisk},
+		{name: "write locked", ema: 0o4, start: 0o6605, want: rfStaWriteLock},
+		{name: "not write locked", ema: 0o10, start: 0o6605, want: 0},
+	}
+	for _, c := range cases {
+		rf := NewRF08()
+		if err := rf.OpenImage(filename, 1, false); err != nil {
+			t.Fatal(err)
+		}
+		// Protect 0o40000-0o77777
+		rf.SetWriteProtect(0, 1, true)
+		p := newDeviceMachine(t, rf, rfRoutine(c.ema, 0, 0, c.start),
+			map[uint]uint{fhWCAddr: 0o7777, fhWCAddr + 1: 0o777})
+		if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+			t.Fatal(err)
+		}
+		if got := rf.statusReg() & rfStaErrors; got != c.want {
+			t.Errorf("%s - got errors: %04o, want: %04o", c.name, got, c.want)
+		}
+		if err := rf.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRF08_DSAC(t *testing.T) {
+	rf := NewRF08()
+	newDeviceMachine(t, rf, nil, nil)
+
+	// DSAC clears the AC whether or not it skips
+	rf.da = rf.position()
+	if pc, lac, _ := rf.IOT(0o6612, 0o200, 0o1234); pc != 0o201 || lac != 0 {
+		t.Errorf("got PC: %04o, AC: %04o, want PC: 0201, AC: 0000", pc, lac)
+	}
+	rf.da = rf.position() + 1
+	if pc, lac, _ := rf.IOT(0o6612, 0o200, 0o1234); pc != 0o200 || lac != 0 {
+		t.Errorf("got PC: %04o, AC: %04o, want PC: 0200, AC: 0000", pc, lac)
+	}
+}
+
+func TestRF08_interrupt_with_TTY(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rf08")
+	rf := NewRF08()
+	defer rf.Close()
+	if err := rf.OpenImage(filename, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	routine := rfRoutine(0, rfStaDoneIE, 0o100, 0o6603)
+	routine[0o206] = 0o6001 // ION
+	routine[0o207] = 0o5207 // JMP 207
+	routine[0o1] = 0o7402   // HLT
+	p := newDeviceMachine(t, rf, routine,
+		map[uint]uint{fhWCAddr: 0o7700, fhWCAddr + 1: 0o777})
+	if err := p.AddDevice(tty); err != nil {
+		t.Fatal(err)
+	}
+	hlt, _, err := p.Run(50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hlt || p.pc != 0o2 || !rf.done {
+		t.Errorf("interrupt not taken, PC: %04o", p.pc)
+	}
+}
@@ -0,0 +1,347 @@
+/*
+ * An RK8-E disk controller with up to four RK05 drives
+ *
+ * The controller is device 74.  Each RK05 disk cartridge has 203
+ * cylinders of two surfaces with 16 sectors of 256 words, giving
+ * 6496 blocks.  A block is numbered by cylinder, surface and then
+ * sector.  Data is transferred directly to memory using data breaks
+ * once the seek and transfer time has passed.  Read All and Write All
+ * are treated as Read and Write as headers and checksums aren't
+ * emulated.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	rkDrives            = 4
+	rkCylinders         = 203
+	rkBlocksPerCylinder = 32
+	rkBlocks            = rkCylinders * rkBlocksPerCylinder
+	rkBlockSize         = 256 // Words per block
+)
+
+// Timings in cycles
+const (
+	rkSettleCycles   = instructionsPerSecond / 100  // 10ms to settle after a seek
+	rkCylinderCycles = instructionsPerSecond / 4000 // 0.25ms for each cylinder moved
+	rkBlockCycles    = instructionsPerSecond / 400  // 2.5ms to transfer a block
+)
+
+// Command register
+const (
+	rkCmdIntEnable = 0o400 // Interrupt on done or error
+	rkCmdSeekDone  = 0o200 // Set done when a seek finishes
+	rkCmdHalf      = 0o100 // Transfer half a block, 128 words
+	rkCmdCylHigh   = 0o1   // Bit 12 of the block number
+)
+
+// Functions in bits 0-2 of the command register
+const (
+	rkRead      = 0
+	rkReadAll   = 1
+	rkWriteLock = 2
+	rkSeek      = 3
+	rkWrite     = 4
+	rkWriteAll  = 5
+)
+
+// Status register
+const (
+	rkStaDone      = 0o4000 // Function done
+	rkStaNotReady  = 0o200  // Drive not ready
+	rkStaBusy      = 0o100  // Controller busy when function started
+	rkStaTiming    = 0o40   // Timing error
+	rkStaWriteLock = 0o20   // Write to a write protected drive
+	rkStaCRC       = 0o10   // CRC error
+	rkStaDataLate  = 0o4    // Data request late
+	rkStaDrive     = 0o2    // Drive status error
+	rkStaCylinder  = 0o1    // Cylinder address error
+
+	rkStaErrors = rkStaBusy | rkStaTiming | rkStaWriteLock | rkStaCRC |
+		rkStaDataLate | rkStaDrive | rkStaCylinder
+)
+
+type RK8E struct {
+	p       *PDP8 // The machine attached to
+	drives  [rkDrives]rkDrive
+	cmd     uint   // Command register
+	da      uint   // Disk address register, bits 0-11 of the block number
+	ca      uint   // Current address register
+	status  uint   // Status register
+	busy    bool   // Whether a function is being carried out
+	pending *event // The function in progress, nil if none
+}
+
+// rkDrive is an RK05 drive
+type rkDrive struct {
+	image        DiskImage
+	file         *os.File // The file opened by OpenImage, if any
+	writeProtect bool
+	cyl          uint // The cylinder the heads are at
+}
+
+// NewRK8E returns a controller with no disks attached
+func NewRK8E() *RK8E {
+	return &RK8E{}
+}
+
+// AttachImage attaches the disk image to drive unit
+func (rk *RK8E) AttachImage(unit int, image DiskImage, writeProtect bool) error {
+	if unit < 0 || unit >= rkDrives {
+		return fmt.Errorf("RK8-E: invalid unit: %d", unit)
+	}
+	if err := rk.DetachImage(unit); err != nil {
+		return err
+	}
+	rk.drives[unit] = rkDrive{image: image, writeProtect: writeProtect}
+	return nil
+}
+
+// OpenImage opens the image file filename and attaches it to drive
+// unit.  If the file doesn't exist it is created unless writeProtect
+// is set.  The file is closed when detached.
+func (rk *RK8E) OpenImage(unit int, filename string, writeProtect bool) error {
+	f, err := openImage(filename, writeProtect)
+	if err != nil {
+		return fmt.Errorf("RK8-E: %w", err)
+	}
+	if err := rk.AttachImage(unit, f, writeProtect); err != nil {
+		f.Close()
+		return err
+	}
+	rk.drives[unit].file = f
+	return nil
+}
+
+// DetachImage detaches the disk image from drive unit
+func (rk *RK8E) DetachImage(unit int) error {
+	if unit < 0 || unit >= rkDrives {
+		return fmt.Errorf("RK8-E: invalid unit: %d", unit)
+	}
+	var err error
+	if f := rk.drives[unit].file; f != nil {
+		err = f.Close()
+	}
+	rk.drives[unit] = rkDrive{}
+	return err
+}
+
+// SetWriteProtect sets the write protect switch of drive unit
+func (rk *RK8E) SetWriteProtect(unit int, on bool) {
+	if unit >= 0 && unit < rkDrives {
+		rk.drives[unit].writeProtect = on
+	}
+}
+
+// Attach also cancels any function in progress
+func (rk *RK8E) Attach(p *PDP8) {
+	rk.p = p
+	rk.busy = false
+	rk.pending.cancel()
+}
+
+// Closes any image files opened by OpenImage
+func (rk *RK8E) Close() error {
+	var errs []error
+	for unit := range rk.drives {
+		if err := rk.DetachImage(unit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (rk *RK8E) DeviceNumbers() []int {
+	return []int{0o74}
+}
+
+func (rk *RK8E) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "RK8-E",
+		Description: "RK05 disk cartridge controller",
+	}
+}
+
+// Reset clears the controller
+func (rk *RK8E) Reset() {
+	rk.CAF()
+}
+
+// CAF clears the controller and cancels any function in progress
+func (rk *RK8E) CAF() {
+	rk.cmd = 0
+	rk.da = 0
+	rk.ca = 0
+	rk.status = 0
+	rk.busy = false
+	rk.pending.cancel()
+}
+
+// Interrupt returns if done or an error is set and interrupts are
+// enabled by the command register
+func (rk *RK8E) Interrupt() (bool, error) {
+	return (rk.cmd&rkCmdIntEnable) != 0 &&
+		(rk.status&(rkStaDone|rkStaErrors)) != 0, nil
+}
+
+// IOT returns PC, LAC, error
+func (rk *RK8E) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	switch ir & 0o7 {
+	case 0o1: // DSKP - Skip on done or error
+		if (rk.status & (rkStaDone | rkStaErrors)) != 0 {
+			pc = mask(pc + 1)
+		}
+	case 0o2: // DCLR - Clear as selected by AC bits 10-11
+		switch lac & 0o3 {
+		case 0o0, 0o3: // Clear status
+			rk.status = 0
+		case 0o1: // Clear controller
+			rk.CAF()
+		case 0o2: // Recalibrate the selected drive
+			rk.status = 0
+			rk.seek(0)
+		}
+		lac &= 0o10000
+	case 0o3: // DLAG - Load disk address and go
+		if rk.busy {
+			rk.status |= rkStaBusy
+		} else {
+			rk.da = mask(lac)
+			rk.start()
+		}
+		lac &= 0o10000
+	case 0o4: // DLCA - Load current address
+		rk.ca = mask(lac)
+		lac &= 0o10000
+	case 0o5: // DRST - Read status
+		lac = (lac & 0o10000) | rk.status
+	case 0o6: // DLDC - Load command register and clear status
+		rk.cmd = mask(lac)
+		rk.status = 0
+		lac &= 0o10000
+	case 0o7: // DMAN - Maintenance, not emulated
+	}
+	return pc, lac, nil
+}
+
+// unit returns the drive selected by the command register
+func (rk *RK8E) unit() *rkDrive {
+	return &rk.drives[(rk.cmd>>1)&0o3]
+}
+
+// start starts the function in the command register
+func (rk *RK8E) start() {
+	d := rk.unit()
+	if d.image == nil {
+		rk.status |= rkStaDone | rkStaNotReady | rkStaDrive
+		return
+	}
+	block := (rk.cmd&rkCmdCylHigh)<<12 | rk.da
+	if block >= rkBlocks {
+		rk.status |= rkStaDone | rkStaCylinder
+		return
+	}
+
+	function := rk.cmd >> 9
+	switch function {
+	case rkWriteLock:
+		d.writeProtect = true
+		rk.status |= rkStaDone
+		return
+	case rkSeek:
+		rk.seek(block / rkBlocksPerCylinder)
+		return
+	case rkRead, rkReadAll, rkWrite, rkWriteAll:
+	default:
+		rk.status |= rkStaDone
+		return
+	}
+	write := function == rkWrite || function == rkWriteAll
+	if write && d.writeProtect {
+		rk.status |= rkStaDone | rkStaWriteLock
+		return
+	}
+
+	cycles := rk.seekCycles(d, block/rkBlocksPerCylinder) + rkBlockCycles
+	d.cyl = block / rkBlocksPerCylinder
+	rk.busy = true
+	rk.pending.cancel()
+	rk.pending = rk.p.schedule(rk, rk.p.Cycles()+cycles, func() error {
+		rk.busy = false
+		if d.image == nil { // Detached while busy
+			rk.status |= rkStaDone | rkStaNotReady | rkStaDrive
+			return nil
+		}
+		err := rk.transfer(d, block, write)
+		rk.status |= rkStaDone
+		return err
+	})
+}
+
+// seek moves the heads of the selected drive to cyl.  Done is set
+// straight away unless it is to be set when the seek finishes.
+func (rk *RK8E) seek(cyl uint) {
+	d := rk.unit()
+	cycles := rk.seekCycles(d, cyl)
+	d.cyl = cyl
+	if (rk.cmd & rkCmdSeekDone) == 0 {
+		rk.status |= rkStaDone
+		return
+	}
+	rk.pending.cancel()
+	rk.pending = rk.p.schedule(rk, rk.p.Cycles()+cycles, func() error {
+		rk.status |= rkStaDone
+		return nil
+	})
+}
+
+// seekCycles returns how many cycles it takes d to seek to cyl
+func (rk *RK8E) seekCycles(d *rkDrive, cyl uint) uint64 {
+	if cyl == d.cyl {
+		return 0
+	}
+	moved := int(cyl) - int(d.cyl)
+	if moved < 0 {
+		moved = -moved
+	}
+	return rkSettleCycles + uint64(moved)*rkCylinderCycles
+}
+
+// transfer reads or writes block of d using data breaks
+func (rk *RK8E) transfer(d *rkDrive, block uint, write bool) error {
+	words := make([]uint, rkBlockSize)
+	n := uint(rkBlockSize)
+	if (rk.cmd & rkCmdHalf) != 0 {
+		n /= 2
+	}
+	field := (rk.cmd >> 3) & 0o7
+	pos := int64(block) * rkBlockSize
+	if write {
+		// The rest of a half block is filled with 0
+		for i := uint(0); i < n; i++ {
+			words[i] = rk.p.DataBreakRead(field<<12 | mask(rk.ca+i))
+		}
+		if err := writeWords(d.image, pos, words); err != nil {
+			return fmt.Errorf("RK8-E: %w", err)
+		}
+	} else {
+		if err := readWords(d.image, pos, words); err != nil {
+			return fmt.Errorf("RK8-E: %w", err)
+		}
+		for i := uint(0); i < n; i++ {
+			rk.p.DataBreakWrite(field<<12|mask(rk.ca+i), words[i])
+		}
+	}
+	rk.ca = mask(rk.ca + n)
+	return nil
+}
@@ -0,0 +1,222 @@
+package pdp8
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rkRoutine returns a routine which loads the command register with
+// cmd, the current address with 1000 and then starts a function with
+// the disk address da and waits until done.  Then AC holds the status.
+func rkRoutine(cmd uint, da uint) map[uint]uint {
+	return map[uint]uint{
+		0o200: 0o1220, // TAD 220
+		0o201: 0o6746, // DLDC
+		0o202: 0o1221, // TAD 221
+		0o203: 0o6744, // DLCA
+		0o204: 0o1222, // TAD 222
+		0o205: 0o6743, // DLAG
+		0o206: 0o6741, // DSKP
+		0o207: 0o5206, // JMP 206
+		0o210: 0o6745, // DRST
+		0o211: 0o7402, // HLT
+		0o220: cmd,
+		0o221: 0o1000,
+		0o222: da,
+	}
+}
+
+func TestRK8E_write_read(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rk05")
+	rk := NewRK8E()
+	defer rk.Close()
+	if err := rk.OpenImage(1, filename, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write block 0o2001 on unit 1 from field 1
+	p := newDeviceMachine(t, rk, rkRoutine(0o4012, 0o2001), nil)
+	for i := uint(0); i < rkBlockSize; i++ {
+		p.mem[0o11000+i] = 0o7000 + i
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.lac != rkStaDone {
+		t.Fatalf("write - got status: %04o, want: %04o", p.lac, rkStaDone)
+	}
+	if c := p.Cycles(); c < rkBlockCycles {
+		t.Errorf("write - got cycles: %d, want at least: %d", c, rkBlockCycles)
+	}
+
+	// Read it back to field 0
+	p = newDeviceMachine(t, rk, rkRoutine(0o0002, 0o2001), nil)
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.lac != rkStaDone {
+		t.Fatalf("read - got status: %04o, want: %04o", p.lac, rkStaDone)
+	}
+	for i := uint(0); i < rkBlockSize; i++ {
+		if got, want := p.mem[0o1000+i], 0o7000+i; got != want {
+			t.Fatalf("mem[%05o] got: %04o, want: %04o", 0o1000+i, got, want)
+		}
+	}
+	if p.mem[0o1000+rkBlockSize] != 0 {
+		t.Error("read more than a block")
+	}
+}
+
+func TestRK8E_half_block(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rk05")
+	rk := NewRK8E()
+	defer rk.Close()
+	if err := rk.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	p := newDeviceMachine(t, rk, rkRoutine(0o0000, 0o7), nil)
+	for i := uint(0); i < rkBlockSize; i++ {
+		p.mem[0o1000+i] = 0o1234
+	}
+	words := make([]uint, rkBlockSize)
+	for i := range words {
+		words[i] = 0o4321
+	}
+	if err := writeWords(rk.drives[0].image, 7*rkBlockSize, words); err != nil {
+		t.Fatal(err)
+	}
+
+	// Half block read
+	p.mem[0o220] = rkCmdHalf
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.mem[0o1177] != 0o4321 || p.mem[0o1200] != 0o1234 {
+		t.Errorf("read - got mem[1177]: %04o, mem[1200]: %04o",
+			p.mem[0o1177], p.mem[0o1200])
+	}
+	if rk.ca != 0o1200 {
+		t.Errorf("got current address: %04o, want: 1200", rk.ca)
+	}
+
+	// Half block write fills the rest of the block with 0
+	p.pc = 0o200
+	p.lac = 0
+	p.mem[0o220] = rkWrite<<9 | rkCmdHalf
+	if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := readWords(rk.drives[0].image, 7*rkBlockSize, words); err != nil {
+		t.Fatal(err)
+	}
+	if words[0o177] != 0o4321 || words[0o200] != 0 {
+		t.Errorf("write - got word 177: %04o, word 200: %04o",
+			words[0o177], words[0o200])
+	}
+}
+
+func TestRK8E_data_break_observed(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rk05")
+	rk := NewRK8E()
+	defer rk.Close()
+	if err := rk.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	p := newDeviceMachine(t, rk, rkRoutine(rkCmdHalf, 0), nil)
+	for i := uint(0); i < rkBlockSize; i++ {
+		p.mem[0o1000+i] = 0o1234
+	}
+	p.SetHistorySize(1)
+	o := &recordingObserver{}
+	p.AddObserver(o)
+
+	// Stop at the end of the cycle in which the block is read
+	if _, err := p.RunUntil(MemoryEquals(0o1177, 0)); err != nil {
+		t.Fatal(err)
+	}
+	writes := 0
+	for _, e := range o.events {
+		if strings.HasPrefix(e, "write ") {
+			writes++
+		}
+	}
+	if writes != rkBlockSize/2 {
+		t.Errorf("got %d writes observed, want: %d", writes, rkBlockSize/2)
+	}
+
+	// The data breaks are undone with the instruction
+	if err := p.StepBack(); err != nil {
+		t.Fatal(err)
+	}
+	if p.mem[0o1000] != 0o1234 || p.mem[0o1177] != 0o1234 {
+		t.Errorf("got mem[1000]: %04o, mem[1177]: %04o, want: 1234",
+			p.mem[0o1000], p.mem[0o1177])
+	}
+}
+
+func TestRK8E_errors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rk05")
+	cases := []struct {
+		name       string
+		cmd        uint
+		da         uint
+		wantStatus uint
+	}{
+		{name: "write protected", cmd: 0o4000, da: 0,
+			wantStatus: rkStaDone | rkStaWriteLock},
+		{name: "no disk", cmd: 0o0006, da: 0,
+			wantStatus: rkStaDone | rkStaNotReady | rkStaDrive},
+		{name: "cylinder", cmd: 0o0001, da: 0o7777,
+			wantStatus: rkStaDone | rkStaCylinder},
+		{name: "seek", cmd: 0o3000, da: 0o7777,
+			wantStatus: rkStaDone},
+	}
+	for _, c := range cases {
+		rk := NewRK8E()
+		if err := rk.OpenImage(0, filename, false); err != nil {
+			t.Fatal(err)
+		}
+		rk.SetWriteProtect(0, true)
+		p := newDeviceMachine(t, rk, rkRoutine(c.cmd, c.da), nil)
+		if _, err := p.RunUntil(Halted(), CycleBudget(50000)); err != nil {
+			t.Fatal(err)
+		}
+		if p.lac != c.wantStatus {
+			t.Errorf("%s - got status: %04o, want: %04o",
+				c.name, p.lac, c.wantStatus)
+		}
+		if err := rk.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRK8E_interrupt(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rk05")
+	rk := NewRK8E()
+	defer rk.Close()
+	if err := rk.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	routine := rkRoutine(rkCmdIntEnable, 0o100)
+	routine[0o206] = 0o6001 // ION
+	routine[0o207] = 0o5207 // JMP 207
+	routine[0o1] = 0o7402   // HLT
+	p := newDeviceMachine(t, rk, routine, nil)
+	hlt, _, err := p.Run(50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hlt || p.pc != 0o2 {
+		t.Errorf("interrupt not taken, PC: %04o", p.pc)
+	}
+
+	// DCLR clears done and so the interrupt
+	if _, _, err := rk.IOT(0o6742, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := rk.Interrupt(); got {
+		t.Error("interrupt still raised after DCLR")
+	}
+}
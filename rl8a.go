@@ -0,0 +1,525 @@
+/*
+ * An RL8-A disk controller with up to four RL01 or RL02 drives
+ *
+ * The controller is devices 60 and 61.  An RL01 has 256 cylinders and
+ * an RL02 has 512, each of two surfaces with 40 sectors of 256 bytes.
+ * In 12-bit mode a sector holds 128 words, each stored in two bytes
+ * with the most significant first as SIMH does, and in 8-bit mode
+ * each word transferred is a byte.  The last track holds the bad
+ * sector file which is written to new images.  Data is transferred
+ * directly to memory using data breaks once the seek and transfer time
+ * has passed.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// RLDriveType is the type of an RL drive
+type RLDriveType int
+
+const (
+	RL01 RLDriveType = iota // 5MB
+	RL02                    // 10MB
+)
+
+// cylinders returns the number of cylinders of the drive type
+func (t RLDriveType) cylinders() uint {
+	if t == RL02 {
+		return 512
+	}
+	return 256
+}
+
+// ImageSize returns the size in bytes of an image for the drive type
+func (t RLDriveType) ImageSize() int64 {
+	return int64(t.cylinders()) * rlSurfaces * rlSectors * rlSectorBytes
+}
+
+const (
+	rlDrives      = 4
+	rlSurfaces    = 2
+	rlSectors     = 40  // Sectors per track
+	rlSectorBytes = 256 // Bytes per sector
+	rlSectorWords = 128 // Words per sector in 12-bit mode
+	rlBadSectors  = 10  // Sectors used by the bad sector file
+)
+
+// Timings in cycles
+const (
+	rlSettleCycles   = instructionsPerSecond / 200   // 5ms to settle after a seek
+	rlCylinderCycles = instructionsPerSecond / 10000 // 0.1ms for each cylinder moved
+	rlSectorCycles   = instructionsPerSecond / 1600  // 0.625ms to transfer a sector
+)
+
+// Command register A
+const (
+	rlCsaDirection = 0o4000 // Seek towards higher cylinders
+	rlCsaHead      = 0o2000 // Head select
+	rlCsaDiff      = 0o777  // Cylinder difference
+)
+
+// Command register B
+const (
+	rlCsbIntEnable = 0o10 // Interrupt on done
+	rlCsb8Bit      = 0o1  // 8-bit mode
+)
+
+// Functions in bits 0-2 of command register B
+const (
+	rlMaintenance = 0
+	rlResetDrive  = 1
+	rlGetStatus   = 2
+	rlSeek        = 3
+	rlReadHeader  = 4
+	rlWrite       = 5
+	rlRead        = 6
+	rlReadNoHdr   = 7
+)
+
+// Error register
+const (
+	rlErrDrive      = 0o4000 // Drive error
+	rlErrHeader     = 0o1000 // Header not found
+	rlErrIncomplete = 0o400  // Transfer went past the end of the track
+)
+
+// Drive status
+const (
+	rlDsLockOn    = 0o5      // Heads locked on a cylinder
+	rlDsBrushHome = 0o10     // Brushes are home
+	rlDsHeadsOut  = 0o20     // Heads are over the disk
+	rlDsHead      = 0o100    // The head selected
+	rlDsRL02      = 0o200    // Drive is an RL02
+	rlDsWriteLock = 0o20000  // Write protected
+	rlDsWriteErr  = 0o100000 // Write to a write protected drive
+)
+
+type RL8A struct {
+	p       *PDP8 // The machine attached to
+	drives  [rlDrives]rlDrive
+	csa     uint   // Command register A
+	csb     uint   // Command register B
+	ma      uint   // Memory address
+	wc      uint   // Word count, as a negative number
+	sa      uint   // Sector address
+	er      uint   // Error register
+	silo    []uint // Bytes to be read with RRSI
+	done    bool   // Done flag
+	busy    bool   // Whether a function is being carried out
+	pending *event // The function in progress, nil if none
+}
+
+// rlDrive is an RL01 or RL02 drive
+type rlDrive struct {
+	image        DiskImage
+	file         *os.File // The file opened by OpenImage, if any
+	driveType    RLDriveType
+	writeProtect bool
+	writeErr     bool // A write was attempted while write protected
+	cyl          uint // The cylinder the heads are at
+	head         uint
+}
+
+// NewRL8A returns a controller with no disks attached
+func NewRL8A() *RL8A {
+	return &RL8A{}
+}
+
+// AttachImage attaches the disk image to drive unit
+func (rl *RL8A) AttachImage(unit int, image DiskImage, driveType RLDriveType, writeProtect bool) error {
+	if unit < 0 || unit >= rlDrives {
+		return fmt.Errorf("RL8-A: invalid unit: %d", unit)
+	}
+	if err := rl.DetachImage(unit); err != nil {
+		return err
+	}
+	rl.drives[unit] = rlDrive{
+		image:        image,
+		driveType:    driveType,
+		writeProtect: writeProtect,
+	}
+	return nil
+}
+
+// OpenImage opens the image file filename and attaches it to drive
+// unit.  If the file doesn't exist it is created with a bad sector
+// file unless writeProtect is set.  The file is closed when detached.
+func (rl *RL8A) OpenImage(unit int, filename string, driveType RLDriveType, writeProtect bool) error {
+	f, err := openImage(filename, writeProtect)
+	if err != nil {
+		return fmt.Errorf("RL8-A: %w", err)
+	}
+	fi, err := f.Stat()
+	if err == nil && fi.Size() == 0 && !writeProtect {
+		err = WriteRLBadSectorFile(f, driveType)
+	}
+	if err == nil {
+		err = rl.AttachImage(unit, f, driveType, writeProtect)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("RL8-A: %w", err)
+	}
+	rl.drives[unit].file = f
+	return nil
+}
+
+// WriteRLBadSectorFile writes an empty bad sector file to the last
+// track of image.  This also sets the size of the image.
+func WriteRLBadSectorFile(image DiskImage, driveType RLDriveType) error {
+	// A pack serial number of 0, two words of 0 and then no bad
+	// sectors, which are marked with 177777
+	buf := make([]byte, rlSectorBytes)
+	for i := 8; i < len(buf); i++ {
+		buf[i] = 0o377
+	}
+	pos := driveType.ImageSize() - rlSectors*rlSectorBytes
+	for i := 0; i < rlSectors; i++ {
+		if i == rlBadSectors {
+			// The rest of the track is unused
+			buf = make([]byte, rlSectorBytes)
+		}
+		if _, err := image.WriteAt(buf, pos); err != nil {
+			return err
+		}
+		pos += rlSectorBytes
+	}
+	return nil
+}
+
+// DetachImage detaches the disk image from drive unit
+func (rl *RL8A) DetachImage(unit int) error {
+	if unit < 0 || unit >= rlDrives {
+		return fmt.Errorf("RL8-A: invalid unit: %d", unit)
+	}
+	var err error
+	if f := rl.drives[unit].file; f != nil {
+		err = f.Close()
+	}
+	rl.drives[unit] = rlDrive{}
+	return err
+}
+
+// SetWriteProtect sets the write protect switch of drive unit
+func (rl *RL8A) SetWriteProtect(unit int, on bool) {
+	if unit >= 0 && unit < rlDrives {
+		rl.drives[unit].writeProtect = on
+	}
+}
+
+// Attach also cancels any function in progress
+func (rl *RL8A) Attach(p *PDP8) {
+	rl.p = p
+	rl.busy = false
+	rl.pending.cancel()
+}
+
+// Closes any image files opened by OpenImage
+func (rl *RL8A) Close() error {
+	var errs []error
+	for unit := range rl.drives {
+		if err := rl.DetachImage(unit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (rl *RL8A) DeviceNumbers() []int {
+	return []int{0o60, 0o61}
+}
+
+func (rl *RL8A) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "RL8-A",
+		Description: "RL01/RL02 disk cartridge controller",
+	}
+}
+
+// Reset clears the controller
+func (rl *RL8A) Reset() {
+	rl.CAF()
+}
+
+// CAF clears the controller and cancels any function in progress
+func (rl *RL8A) CAF() {
+	rl.csa = 0
+	rl.csb = 0
+	rl.ma = 0
+	rl.wc = 0
+	rl.sa = 0
+	rl.er = 0
+	rl.silo = nil
+	rl.done = false
+	rl.busy = false
+	rl.pending.cancel()
+}
+
+// Interrupt returns if done is set and interrupts are enabled by
+// command register B
+func (rl *RL8A) Interrupt() (bool, error) {
+	return rl.done && (rl.csb&rlCsbIntEnable) != 0, nil
+}
+
+// IOT returns PC, LAC, error
+func (rl *RL8A) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	link := lac & 0o10000
+	ac := mask(lac)
+	device := (ir >> 3) & 0o77
+	switch device {
+	case 0o60:
+		switch ir & 0o7 {
+		case 0o0: // RLDC - Clear controller
+			rl.CAF()
+			ac = 0
+		case 0o1: // RLSD - Skip on done and clear done
+			if rl.done {
+				pc = mask(pc + 1)
+			}
+			rl.done = false
+		case 0o2: // RLMA - Load memory address
+			rl.ma = ac
+			ac = 0
+		case 0o3: // RLCA - Load command register A
+			rl.csa = ac
+			ac = 0
+		case 0o4: // RLCB - Load command register B and go
+			if !rl.busy {
+				rl.csb = ac
+				rl.start()
+			}
+			ac = 0
+		case 0o5: // RLSA - Load sector address
+			rl.sa = ac >> 6
+			ac = 0
+		case 0o7: // RLWC - Load word count
+			rl.wc = ac
+			ac = 0
+		}
+	case 0o61:
+		switch ir & 0o7 {
+		case 0o0: // RRER - Read error register
+			ac = rl.er
+		case 0o1: // RRWC - Read word count
+			ac = rl.wc
+		case 0o2: // RRCA - Read command register A
+			ac = rl.csa
+		case 0o3: // RRCB - Read command register B
+			ac = rl.csb
+		case 0o4: // RRSA - Read sector address
+			ac = rl.sa << 6
+		case 0o5: // RRSI - Read silo
+			ac = 0
+			if len(rl.silo) > 0 {
+				ac = rl.silo[0]
+				rl.silo = rl.silo[1:]
+			}
+		case 0o7: // RLSE - Skip on error
+			if rl.er != 0 {
+				pc = mask(pc + 1)
+			}
+		}
+	}
+	return pc, link | ac, nil
+}
+
+// unit returns the drive selected by command register B
+func (rl *RL8A) unit() *rlDrive {
+	return &rl.drives[(rl.csb>>7)&0o3]
+}
+
+// status returns the drive status of d
+func (rl *RL8A) status(d *rlDrive) uint {
+	if d.image == nil {
+		return 0
+	}
+	s := uint(rlDsLockOn | rlDsBrushHome | rlDsHeadsOut)
+	if d.head == 1 {
+		s |= rlDsHead
+	}
+	if d.driveType == RL02 {
+		s |= rlDsRL02
+	}
+	if d.writeProtect {
+		s |= rlDsWriteLock
+	}
+	if d.writeErr {
+		s |= rlDsWriteErr
+	}
+	return s
+}
+
+// setSilo puts w in the silo to be read as two bytes, most
+// significant first
+func (rl *RL8A) setSilo(w uint) {
+	rl.silo = []uint{(w >> 8) & 0o377, w & 0o377}
+}
+
+// start starts the function in command register B.  Done is set once
+// the function has finished.
+func (rl *RL8A) start() {
+	rl.er = 0
+	rl.done = false
+	d := rl.unit()
+	if d.image == nil {
+		rl.finish(0, rlErrDrive, nil)
+		return
+	}
+
+	switch rl.csb >> 9 {
+	case rlMaintenance:
+		rl.finish(0, 0, nil)
+	case rlResetDrive:
+		d.writeErr = false
+		rl.finish(0, 0, nil)
+	case rlGetStatus:
+		rl.setSilo(rl.status(d))
+		rl.finish(0, 0, nil)
+	case rlSeek:
+		cyl := int(d.cyl)
+		if (rl.csa & rlCsaDirection) != 0 {
+			cyl += int(rl.csa & rlCsaDiff)
+		} else {
+			cyl -= int(rl.csa & rlCsaDiff)
+		}
+		cyl = max(0, min(cyl, int(d.driveType.cylinders())-1))
+		cycles := rl.seekCycles(d, uint(cyl))
+		d.cyl = uint(cyl)
+		d.head = 0
+		if (rl.csa & rlCsaHead) != 0 {
+			d.head = 1
+		}
+		rl.finish(cycles, 0, nil)
+	case rlReadHeader:
+		rl.setSilo(d.cyl<<7 | d.head<<6 | rl.sa)
+		rl.finish(rlSectorCycles, 0, nil)
+	case rlWrite:
+		if d.writeProtect {
+			d.writeErr = true
+			rl.finish(0, rlErrDrive, nil)
+			return
+		}
+		rl.startTransfer(d, true)
+	case rlRead, rlReadNoHdr:
+		rl.startTransfer(d, false)
+	}
+}
+
+// startTransfer starts reading or writing from the sector address
+// to the end of the track at most
+func (rl *RL8A) startTransfer(d *rlDrive, write bool) {
+	if rl.sa >= rlSectors {
+		rl.finish(rlSectorCycles, rlErrHeader, nil)
+		return
+	}
+	perSector := uint(rlSectorWords)
+	if (rl.csb & rlCsb8Bit) != 0 {
+		perSector = rlSectorBytes
+	}
+	n := 0o10000 - rl.wc
+	var er uint
+	if limit := (rlSectors - rl.sa) * perSector; n > limit {
+		n = limit
+		er = rlErrIncomplete
+	}
+	sectors := (n + perSector - 1) / perSector
+	cycles := uint64(sectors) * rlSectorCycles
+	rl.finish(cycles, er, func() error {
+		if d.image == nil { // Detached while busy
+			rl.er |= rlErrDrive
+			return nil
+		}
+		return rl.transfer(d, n, write)
+	})
+}
+
+// finish sets done, and er in the error register, after cycles.  If
+// fn is passed it is called first.
+func (rl *RL8A) finish(cycles uint64, er uint, fn func() error) {
+	if cycles == 0 && fn == nil {
+		rl.er = er
+		rl.done = true
+		return
+	}
+	rl.busy = true
+	rl.pending.cancel()
+	rl.pending = rl.p.schedule(rl, rl.p.Cycles()+cycles, func() error {
+		var err error
+		if fn != nil {
+			err = fn()
+		}
+		rl.busy = false
+		rl.er |= er
+		rl.done = true
+		return err
+	})
+}
+
+// seekCycles returns how many cycles it takes d to seek to cyl
+func (rl *RL8A) seekCycles(d *rlDrive, cyl uint) uint64 {
+	if cyl == d.cyl {
+		return rlSettleCycles
+	}
+	moved := int(cyl) - int(d.cyl)
+	if moved < 0 {
+		moved = -moved
+	}
+	return rlSettleCycles + uint64(moved)*rlCylinderCycles
+}
+
+// transfer reads or writes n words, or bytes in 8-bit mode, of d
+// using data breaks.  The rest of the last sector written is filled
+// with 0.
+func (rl *RL8A) transfer(d *rlDrive, n uint, write bool) error {
+	eightBit := (rl.csb & rlCsb8Bit) != 0
+	field := (rl.csb >> 4) & 0o7
+	sectors := (n + rlSectorWords - 1) / rlSectorWords
+	if eightBit {
+		sectors = (n + rlSectorBytes - 1) / rlSectorBytes
+	}
+	track := d.cyl*rlSurfaces + d.head
+	pos := int64(track*rlSectors+rl.sa) * rlSectorBytes
+	buf := make([]byte, sectors*rlSectorBytes)
+
+	if write {
+		for i := uint(0); i < n; i++ {
+			w := rl.p.DataBreakRead(field<<12 | mask(rl.ma+i))
+			if eightBit {
+				buf[i] = byte(w)
+			} else {
+				buf[i*2] = byte(w >> 8)
+				buf[i*2+1] = byte(w)
+			}
+		}
+		if _, err := d.image.WriteAt(buf, pos); err != nil {
+			return fmt.Errorf("RL8-A: %w", err)
+		}
+	} else {
+		if _, err := d.image.ReadAt(buf, pos); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("RL8-A: %w", err)
+		}
+		for i := uint(0); i < n; i++ {
+			var w uint
+			if eightBit {
+				w = uint(buf[i])
+			} else {
+				w = uint(buf[i*2])<<8 | uint(buf[i*2+1])
+			}
+			rl.p.DataBreakWrite(field<<12|mask(rl.ma+i), w)
+		}
+	}
+	rl.ma = mask(rl.ma + n)
+	rl.wc = mask(rl.wc + n)
+	rl.sa += sectors
+	return nil
+}
@@ -0,0 +1,202 @@
+package pdp8
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rlStep loads AC with value and then executes iot
+type rlStep struct {
+	value uint
+	iot   uint
+}
+
+// newRLMachine returns a machine with a routine which executes steps,
+// waiting for done after each RLCB, then reads the error register
+// into AC and halts
+func newRLMachine(t *testing.T, rl *RL8A, steps []rlStep) *PDP8 {
+	t.Helper()
+	p := newDeviceMachine(t, rl, nil, nil)
+	addr := uint(0o200)
+	add := func(ws ...uint) {
+		for _, w := range ws {
+			p.mem[addr] = w
+			addr++
+		}
+	}
+	for i, s := range steps {
+		p.mem[0o20+i] = s.value
+		add(0o7200, 0o1020+uint(i), s.iot) // CLA, TAD value, IOT
+		if s.iot == 0o6604 {
+			add(0o6601, 0o5200|(addr&0o177)) // RLSD, JMP .-1
+		}
+	}
+	add(0o7200, 0o6610, 0o7402) // CLA, RRER, HLT
+	return p
+}
+
+func runRL(t *testing.T, p *PDP8) {
+	t.Helper()
+	if _, err := p.RunUntil(Halted(), CycleBudget(100000)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRL8A_new_image(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rl01")
+	rl := NewRL8A()
+	if err := rl.OpenImage(0, filename, RL01, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(b)) != RL01.ImageSize() || len(b) != 5242880 {
+		t.Fatalf("got size: %d, want: %d", len(b), RL01.ImageSize())
+	}
+	lastTrack := len(b) - rlSectors*rlSectorBytes
+	for i, want := range map[int]byte{0: 0, 7: 0, 8: 0o377, 255: 0o377,
+		9*rlSectorBytes + 255: 0o377, 10 * rlSectorBytes: 0} {
+		if got := b[lastTrack+i]; got != want {
+			t.Errorf("byte %d of last track got: %03o, want: %03o", i, got, want)
+		}
+	}
+}
+
+func TestRL8A_seek_write_read(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rl02")
+	rl := NewRL8A()
+	defer rl.Close()
+	if err := rl.OpenImage(2, filename, RL02, false); err != nil {
+		t.Fatal(err)
+	}
+
+	seek := []rlStep{
+		{0o4000 | 0o2000 | 0o5, 0o6603}, // RLCA cylinder +5, head 1
+		{0o3000 | 0o400, 0o6604},        // RLCB seek unit 2
+	}
+	transfer := func(function uint, ma uint, field uint) []rlStep {
+		return []rlStep{
+			{ma, 0o6602},                             // RLMA
+			{0o3 << 6, 0o6605},                       // RLSA sector 3
+			{0o7600, 0o6607},                         // RLWC 200 words
+			{function<<9 | 0o400 | field<<4, 0o6604}, // RLCB unit 2
+		}
+	}
+
+	p := newRLMachine(t, rl, append(seek, transfer(rlWrite, 0o1000, 1)...))
+	for i := uint(0); i < 0o200; i++ {
+		p.mem[0o11000+i] = 0o7000 + i
+	}
+	runRL(t, p)
+	if p.lac != 0 {
+		t.Fatalf("write - got error register: %04o", p.lac)
+	}
+	if rl.sa != 4 || rl.wc != 0 || rl.ma != 0o1200 {
+		t.Errorf("write - got sector: %o, word count: %04o, memory address: %04o",
+			rl.sa, rl.wc, rl.ma)
+	}
+
+	f := rl.drives[2].image
+	buf := make([]byte, 2)
+	pos := int64(((5*2+1)*rlSectors + 3) * rlSectorBytes)
+	if _, err := f.ReadAt(buf, pos+2); err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != 0o7001>>8 || buf[1] != 0o7001&0o377 {
+		t.Errorf("got bytes: %03o, want word: 7001", buf)
+	}
+
+	// The heads are still on the cylinder
+	p = newRLMachine(t, rl, transfer(rlRead, 0o2000, 0))
+	runRL(t, p)
+	if p.lac != 0 {
+		t.Fatalf("read - got error register: %04o", p.lac)
+	}
+	for i := uint(0); i < 0o200; i++ {
+		if got, want := p.mem[0o2000+i], 0o7000+i; got != want {
+			t.Fatalf("mem[%05o] got: %04o, want: %04o", 0o2000+i, got, want)
+		}
+	}
+
+	// In 8-bit mode each byte is read to a word
+	steps := transfer(rlRead, 0o3000, 0)
+	steps[3].value |= rlCsb8Bit
+	p = newRLMachine(t, rl, steps)
+	runRL(t, p)
+	if p.mem[0o3002] != 0o7001>>8 || p.mem[0o3003] != 0o7001&0o377 {
+		t.Errorf("8-bit mode got: %04o %04o", p.mem[0o3002], p.mem[0o3003])
+	}
+}
+
+func TestRL8A_errors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rl01")
+	cases := []struct {
+		name  string
+		steps []rlStep
+		want  uint
+	}{
+		{name: "write protected",
+			steps: []rlStep{{rlWrite << 9, 0o6604}},
+			want:  rlErrDrive},
+		{name: "no drive",
+			steps: []rlStep{{rlRead<<9 | 0o200, 0o6604}},
+			want:  rlErrDrive},
+		{name: "bad sector",
+			steps: []rlStep{{0o50 << 6, 0o6605}, {rlRead << 9, 0o6604}},
+			want:  rlErrHeader},
+		{name: "past end of track",
+			steps: []rlStep{{0o47 << 6, 0o6605}, {0o7400, 0o6607},
+				{rlRead << 9, 0o6604}},
+			want: rlErrIncomplete},
+	}
+	for _, c := range cases {
+		rl := NewRL8A()
+		if err := rl.OpenImage(0, filename, RL01, false); err != nil {
+			t.Fatal(err)
+		}
+		rl.SetWriteProtect(0, true)
+		p := newRLMachine(t, rl, c.steps)
+		runRL(t, p)
+		if p.lac != c.want {
+			t.Errorf("%s - got error register: %04o, want: %04o",
+				c.name, p.lac, c.want)
+		}
+		if err := rl.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRL8A_get_status(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rl02")
+	rl := NewRL8A()
+	defer rl.Close()
+	if err := rl.OpenImage(0, filename, RL02, true); err == nil {
+		t.Fatal("OpenImage: no error for a missing write protected image")
+	}
+	if err := rl.OpenImage(0, filename, RL02, false); err != nil {
+		t.Fatal(err)
+	}
+	rl.SetWriteProtect(0, true)
+	p := newRLMachine(t, rl, []rlStep{{rlGetStatus << 9, 0o6604}})
+	runRL(t, p)
+
+	want := uint(rlDsLockOn | rlDsBrushHome | rlDsHeadsOut | rlDsRL02 | rlDsWriteLock)
+	var got uint
+	for i := 0; i < 2; i++ {
+		_, ac, err := rl.IOT(0o6615, 0, 0) // RRSI
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = got<<8 | ac
+	}
+	if got != want {
+		t.Errorf("got status: %06o, want: %06o", got, want)
+	}
+}
@@ -0,0 +1,261 @@
+/*
+ * Run the emulator until a condition is met
+ *
+ * Conditions can be combined using AnyOf and AllOf so that
+ * programs using the emulator don't need to keep polling
+ * the machine after each call to Run.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Condition is tested by RunUntil after each instruction is executed
+type Condition interface {
+	// Met returns whether the condition has been satisfied
+	Met(p *PDP8, r *RunInfo) bool
+	String() string
+}
+
+// RunInfo describes what has happened so far in a call to RunUntil
+type RunInfo struct {
+	Cycles         int  // The number of instructions executed
+	Halted         bool // Whether a HLT has just been executed
+	InterruptTaken bool // Whether an interrupt has just been taken
+}
+
+// Stop describes why RunUntil stopped
+type Stop struct {
+	// The condition that was met, this will be nil if a HLT was
+	// executed or a breakpoint hit without meeting any of the conditions
+	Condition Condition
+	// The breakpoint that was hit, if any
+	Breakpoint *Breakpoint
+	State      State // The registers when RunUntil stopped
+	Cycles     int   // The number of instructions executed
+	Halted     bool  // Whether stopped because of a HLT
+}
+
+// RunUntil runs until one of the conditions is met, a HLT is executed
+// or a breakpoint is hit.  The conditions are only tested after each
+// instruction has been executed.
+func (p *PDP8) RunUntil(conds ...Condition) (Stop, error) {
+	var err error
+	r := &RunInfo{}
+
+	if len(conds) == 0 {
+		return Stop{State: p.State()}, errors.New("RunUntil: no conditions")
+	}
+
+	for {
+		r.Halted, r.InterruptTaken, err = p.cycle()
+		var be *BreakError
+		if errors.As(err, &be) {
+			r.Cycles++
+			stop := Stop{
+				Breakpoint: be.Breakpoint,
+				State:      p.State(),
+				Cycles:     r.Cycles,
+				Halted:     r.Halted,
+			}
+			return stop, nil
+		}
+		if err != nil {
+			return Stop{State: p.State(), Cycles: r.Cycles}, err
+		}
+		r.Cycles++
+
+		for _, c := range conds {
+			if c.Met(p, r) {
+				stop := Stop{
+					Condition: c,
+					State:     p.State(),
+					Cycles:    r.Cycles,
+					Halted:    r.Halted,
+				}
+				return stop, nil
+			}
+		}
+		if r.Halted {
+			return Stop{State: p.State(), Cycles: r.Cycles, Halted: true}, nil
+		}
+	}
+}
+
+type pcEquals struct {
+	pc uint
+}
+
+// PCEquals is met when the PC, the address of the next instruction to
+// execute, equals pc.  Bits 12-14 of pc are the instruction field.
+func PCEquals(pc uint) Condition {
+	return &pcEquals{pc: pc & 0o77777}
+}
+
+func (c *pcEquals) Met(p *PDP8, r *RunInfo) bool {
+	return p.ifr<<12|p.pc == c.pc
+}
+
+func (c *pcEquals) String() string {
+	return fmt.Sprintf("PC = %05o", c.pc)
+}
+
+type halted struct{}
+
+// Halted is met when a HLT instruction is executed
+func Halted() Condition {
+	return halted{}
+}
+
+func (c halted) Met(p *PDP8, r *RunInfo) bool {
+	return r.Halted
+}
+
+func (c halted) String() string {
+	return "HLT"
+}
+
+// Output is implemented by buffers such as bytes.Buffer
+// which can be used to capture the output of a device
+type Output interface {
+	Bytes() []byte
+}
+
+type outputContains struct {
+	out     Output
+	want    []byte
+	lastLen int  // Length of output when last checked
+	found   bool // Whether want was found when last checked
+}
+
+// OutputContains is met when out contains want
+func OutputContains(out Output, want []byte) Condition {
+	return &outputContains{out: out, want: want, lastLen: -1}
+}
+
+func (c *outputContains) Met(p *PDP8, r *RunInfo) bool {
+	b := c.out.Bytes()
+	// Only search the output if it has changed
+	if len(b) != c.lastLen {
+		c.lastLen = len(b)
+		c.found = bytes.Contains(b, c.want)
+	}
+	return c.found
+}
+
+func (c *outputContains) String() string {
+	return fmt.Sprintf("output contains %q", c.want)
+}
+
+type memoryEquals struct {
+	addr  uint
+	value uint
+}
+
+// MemoryEquals is met when the word at addr equals value.  Bits 12-14
+// of addr are the field.
+func MemoryEquals(addr uint, value uint) Condition {
+	return &memoryEquals{addr: addr & 0o77777, value: mask(value)}
+}
+
+func (c *memoryEquals) Met(p *PDP8, r *RunInfo) bool {
+	return c.addr < uint(len(p.mem)) && p.mem[c.addr] == c.value
+}
+
+func (c *memoryEquals) String() string {
+	return fmt.Sprintf("memory %05o = %04o", c.addr, c.value)
+}
+
+type cycleBudget struct {
+	cycles int
+}
+
+// CycleBudget is met once the number of instructions passed
+// to it have been executed
+func CycleBudget(cycles int) Condition {
+	return &cycleBudget{cycles: cycles}
+}
+
+func (c *cycleBudget) Met(p *PDP8, r *RunInfo) bool {
+	return r.Cycles >= c.cycles
+}
+
+func (c *cycleBudget) String() string {
+	return fmt.Sprintf("cycle budget %d", c.cycles)
+}
+
+type interruptTaken struct{}
+
+// InterruptTaken is met when an interrupt is taken
+func InterruptTaken() Condition {
+	return interruptTaken{}
+}
+
+func (c interruptTaken) Met(p *PDP8, r *RunInfo) bool {
+	return r.InterruptTaken
+}
+
+func (c interruptTaken) String() string {
+	return "interrupt taken"
+}
+
+type anyOf struct {
+	conds []Condition
+}
+
+// AnyOf is met when any of conds are met
+func AnyOf(conds ...Condition) Condition {
+	return &anyOf{conds: conds}
+}
+
+func (c *anyOf) Met(p *PDP8, r *RunInfo) bool {
+	for _, cond := range c.conds {
+		if cond.Met(p, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *anyOf) String() string {
+	return joinConditions(c.conds, " OR ")
+}
+
+type allOf struct {
+	conds []Condition
+}
+
+// AllOf is met when all of conds are met after the same instruction
+func AllOf(conds ...Condition) Condition {
+	return &allOf{conds: conds}
+}
+
+func (c *allOf) Met(p *PDP8, r *RunInfo) bool {
+	for _, cond := range c.conds {
+		if !cond.Met(p, r) {
+			return false
+		}
+	}
+	return len(c.conds) > 0
+}
+
+func (c *allOf) String() string {
+	return joinConditions(c.conds, " AND ")
+}
+
+func joinConditions(conds []Condition, sep string) string {
+	s := make([]string, len(conds))
+	for i, c := range conds {
+		s[i] = c.String()
+	}
+	return "(" + strings.Join(s, sep) + ")"
+}
@@ -0,0 +1,303 @@
+package pdp8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRunUntil_PCEquals(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o7001, // IAC
+		0o202: 0o7001, // IAC
+		0o203: 0o5200, // JMP 200
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cond := PCEquals(0o203)
+	stop, err := p.RunUntil(cond, CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != cond {
+		t.Fatalf("got condition: %v, want: %v", stop.Condition, cond)
+	}
+	if stop.State.PC != 0o203 || stop.State.AC != 3 || stop.Cycles != 3 {
+		t.Errorf("got PC: %04o, AC: %04o, Cycles: %d, want PC: 0203, AC: 0003, Cycles: 3",
+			stop.State.PC, stop.State.AC, stop.Cycles)
+	}
+}
+
+func TestRunUntil_Halted(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o7402, // HLT
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cond := Halted()
+	stop, err := p.RunUntil(cond, CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != cond || !stop.Halted {
+		t.Fatalf("got condition: %v, halted: %t, want: %v, halted: true",
+			stop.Condition, stop.Halted, cond)
+	}
+	if stop.State.PC-1 != 0o201 {
+		t.Errorf("HLT - got PC: %04o, want PC: 0201", stop.State.PC-1)
+	}
+}
+
+// A HLT stops RunUntil even if it isn't one of the conditions
+func TestRunUntil_HLT_not_a_condition(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7402, // HLT
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	stop, err := p.RunUntil(CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != nil || !stop.Halted {
+		t.Errorf("got condition: %v, halted: %t, want: <nil>, halted: true",
+			stop.Condition, stop.Halted)
+	}
+}
+
+func TestRunUntil_OutputContains(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o1210, // TAD 210
+		0o201: 0o6046, // TLS
+		0o202: 0o6041, // TSF
+		0o203: 0o5202, // JMP 202
+		0o204: 0o5201, // JMP 201
+		0o210: 0o0301, // 'A'
+	}
+
+	rw := newDummyReadWriter()
+	ttyOut := &bytes.Buffer{}
+	tty := NewTTY(rw, ttyOut)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cond := OutputContains(ttyOut, []byte("AAA"))
+	stop, err := p.RunUntil(cond, CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != cond {
+		t.Fatalf("got condition: %v, want: %v", stop.Condition, cond)
+	}
+	if ttyOut.String() != "AAA" {
+		t.Errorf("got output: %q, want: \"AAA\"", ttyOut.String())
+	}
+}
+
+func TestRunUntil_MemoryEquals(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o2210, // ISZ 210
+		0o201: 0o5200, // JMP 200
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cond := MemoryEquals(0o210, 5)
+	stop, err := p.RunUntil(cond, CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != cond {
+		t.Fatalf("got condition: %v, want: %v", stop.Condition, cond)
+	}
+	if stop.Cycles != 9 {
+		t.Errorf("got cycles: %d, want: 9", stop.Cycles)
+	}
+}
+
+func TestRunUntil_CycleBudget(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o5200, // JMP 200
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cond := CycleBudget(100)
+	stop, err := p.RunUntil(cond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != cond || stop.Cycles != 100 {
+		t.Errorf("got condition: %v, cycles: %d, want: %v, cycles: 100",
+			stop.Condition, stop.Cycles, cond)
+	}
+}
+
+func TestRunUntil_InterruptTaken(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6001, // ION
+		0o201: 0o6046, // TLS
+		0o202: 0o5202, // JMP 202
+	}
+
+	rw := newDummyReadWriter()
+	tty := NewTTY(rw, rw)
+	defer tty.Close()
+
+	p, err := New(WithDevice(tty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cond := InterruptTaken()
+	stop, err := p.RunUntil(cond, CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != cond {
+		t.Fatalf("got condition: %v, want: %v", stop.Condition, cond)
+	}
+	if stop.State.PC != 1 || stop.State.ION || p.mem[0] != 0o202 {
+		t.Errorf("got PC: %04o, ION: %t, mem[0]: %04o, want PC: 0001, ION: false, mem[0]: 0202",
+			stop.State.PC, stop.State.ION, p.mem[0])
+	}
+}
+
+func TestRunUntil_AllOf_AnyOf(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o2210, // ISZ 210
+		0o202: 0o5200, // JMP 200
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	cond := AllOf(
+		PCEquals(0o202),
+		AnyOf(MemoryEquals(0o210, 3), MemoryEquals(0o210, 7)),
+	)
+	stop, err := p.RunUntil(cond, CycleBudget(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Condition != cond {
+		t.Fatalf("got condition: %v, want: %v", stop.Condition, cond)
+	}
+	if stop.State.AC != 3 {
+		t.Errorf("got AC: %04o, want: 0003", stop.State.AC)
+	}
+}
+
+func TestRunUntil_no_conditions(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RunUntil(); err == nil {
+		t.Error("RunUntil with no conditions didn't return an error")
+	}
+}
+
+func TestRun_cyclesLeft(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o7000, // NOP
+		0o202: 0o5200, // JMP 200
+		0o300: 0o7001, // IAC
+		0o301: 0o7402, // HLT
+	}
+	cases := []struct {
+		name     string
+		pc       uint
+		brk      *Breakpoint
+		wantHlt  bool
+		wantLeft int
+		wantErr  bool
+	}{
+		{name: "no cycles left", pc: 0o200, wantLeft: 0},
+		{name: "halted", pc: 0o300, wantHlt: true, wantLeft: 8},
+		{name: "breakpoint", pc: 0o200, brk: BreakOnPC(0o202),
+			wantLeft: 8, wantErr: true},
+	}
+	for _, c := range cases {
+		p, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for addr, v := range testRoutine {
+			p.mem[addr] = v
+		}
+		p.pc = c.pc
+		if c.brk != nil {
+			p.AddBreakpoint(c.brk)
+		}
+		hlt, left, err := p.Run(10)
+		var be *BreakError
+		if gotErr := errors.As(err, &be); gotErr != c.wantErr {
+			t.Fatalf("%s - got error: %v", c.name, err)
+		}
+		if hlt != c.wantHlt || left != c.wantLeft {
+			t.Errorf("%s - got hlt: %t, cycles left: %d, want hlt: %t, cycles left: %d",
+				c.name, hlt, left, c.wantHlt, c.wantLeft)
+		}
+	}
+}
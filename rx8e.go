@@ -0,0 +1,777 @@
+/*
+ * An RX8E floppy disk controller with two RX01 drives or an RX28 with
+ * two RX02 drives
+ *
+ * The controller is device 75.  Commands, sector and track addresses
+ * and data are passed serially through the interface register one
+ * word, or byte in 8-bit mode, at a time whenever the transfer request
+ * flag is set.  Data moves between the disk and memory through the
+ * sector buffer of the controller using Fill Buffer and Empty Buffer.
+ * A disk has 77 tracks of 26 sectors, each of 128 bytes in single
+ * density or 256 bytes in double density which only the RX02 can use.
+ * In 12-bit mode a sector holds 64 or 128 words packed into the first
+ * three quarters of it.  Images are in SIMH format with the sectors in
+ * physical order so any interleave is left to the program, the DEC
+ * standard is given by RXLogicalSector.  Images have no room to hold
+ * deleted data marks so those of an image opened by OpenImage are kept
+ * in a file named after it with .del added, which has a byte for each
+ * sector set to 1 if the sector has a deleted data mark.  The file
+ * only exists while a sector has a mark.  The marks of an image
+ * attached with AttachImage are only kept while attached.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	rxDrives        = 2
+	rxTracks        = 77
+	rxSectors       = 26  // Sectors per track, numbered from 1
+	rxSDSectorBytes = 128 // Bytes per sector in single density
+	rxDDSectorBytes = 256 // Bytes per sector in double density
+	rxKey           = 0o111
+	rxMarksSuffix   = ".del" // Added to an image's filename for its deleted data marks
+)
+
+// Timings in cycles
+const (
+	rxRevCycles      = instructionsPerSecond / 6            // 360rpm
+	rxSectorCycles   = rxRevCycles / rxSectors              // Cycles for a sector to pass
+	rxTrackCycles    = instructionsPerSecond * 6 / 1000     // 6ms to step a track
+	rxSettleCycles   = instructionsPerSecond * 25 / 1000    // 25ms to settle after a seek
+	rxTransferCycles = instructionsPerSecond * 20 / 1000000 // 20µs between transfer requests
+	rxFormatCycles   = instructionsPerSecond * 15           // 15s to set the media density
+)
+
+// Command register
+const (
+	rxcsFunction = 0o16  // The function
+	rxcsDrive    = 0o20  // The drive selected
+	rxcs8Bit     = 0o100 // 8-bit mode
+	rxcsMaint    = 0o200 // Maintenance, not emulated
+	rxcsDouble   = 0o400 // Double density, RX28 only
+)
+
+// Functions in the command register
+const (
+	rxFill         = 0
+	rxEmpty        = 1
+	rxWrite        = 2
+	rxRead         = 3
+	rxSetDensity   = 4 // RX28 only
+	rxReadStatus   = 5
+	rxWriteDeleted = 6
+	rxReadError    = 7
+)
+
+// Error and status register
+const (
+	rxesCRC        = 0o1   // CRC error, not emulated
+	rxesInitDone   = 0o4   // Initialize has finished
+	rxesRX02       = 0o10  // The controller is an RX28
+	rxesDensityErr = 0o20  // The disk has a different density to the function
+	rxesDouble     = 0o40  // The disk is double density
+	rxesDeleted    = 0o100 // The sector read has a deleted data mark
+	rxesReady      = 0o200 // The drive has a disk
+)
+
+// Error codes
+const (
+	rxErrTrack        = 0o40  // Track greater than 76
+	rxErrSector       = 0o70  // Sector not found
+	rxErrWriteProtect = 0o100 // Write to a write protected disk
+	rxErrNotReady     = 0o110 // No disk in the drive
+	rxErrDensity      = 0o240 // The disk has a different density to the function
+	rxErrKey          = 0o250 // Wrong key to set the media density
+)
+
+// States of the interface
+const (
+	rxIdle       = iota
+	rxFilling    // Taking words from XDR for the sector buffer
+	rxEmptying   // Giving words from the sector buffer to XDR
+	rxWantSector // Waiting for the sector from XDR
+	rxWantTrack  // Waiting for the track from XDR
+	rxWantKey    // Waiting for the key to set the media density from XDR
+	rxBusy       // Carrying out a function
+)
+
+type RX8E struct {
+	p       *PDP8 // The machine attached to
+	rx02    bool  // Whether the controller is an RX28
+	drives  [rxDrives]rxDrive
+	csr     uint // Command register
+	dbr     uint // Interface register
+	buf     [rxDDSectorBytes]byte
+	bptr    uint // Next word or byte of buf to transfer
+	sector  uint
+	track   uint
+	state   int
+	es      uint   // Error and status bits for the last function
+	errCode uint   // Error code of the last function
+	tr      bool   // Transfer request flag
+	done    bool   // Done flag
+	err     bool   // Error flag
+	ie      bool   // Interrupt enable
+	pending *event // The next event of the function in progress, if any
+}
+
+// rxDrive is an RX01 or RX02 drive
+type rxDrive struct {
+	image        DiskImage
+	file         *os.File // The file opened by OpenImage, if any
+	double       bool     // Whether the disk is double density
+	writeProtect bool
+	track        uint          // The track the head is at
+	deleted      map[uint]bool // Sectors with a deleted data mark
+	marksFile    string        // The file the deleted data marks are saved to, if any
+}
+
+// NewRX8E returns an RX8E controller for RX01 drives with no disks
+// attached
+func NewRX8E() *RX8E {
+	return &RX8E{}
+}
+
+// NewRX28 returns an RX28 controller for RX02 drives with no disks
+// attached
+func NewRX28() *RX8E {
+	return &RX8E{rx02: true}
+}
+
+// RXImageSize returns the size in bytes of an image for a single or
+// double density disk
+func RXImageSize(double bool) int64 {
+	return rxTracks * rxSectors * rxSectorBytes(double)
+}
+
+// RXLogicalSector returns the physical track and sector of logical
+// sector n using the DEC standard interleave.  This has a 2:1
+// interleave with a skew of 6 sectors from track to track and doesn't
+// use track 0.
+func RXLogicalSector(n int) (track int, sector int) {
+	track = n / rxSectors
+	i := n % rxSectors
+	sector = i * 2
+	if i >= rxSectors/2 {
+		sector++
+	}
+	sector = (sector+6*track)%rxSectors + 1
+	return track + 1, sector
+}
+
+// rxSectorBytes returns the number of bytes in each sector
+func rxSectorBytes(double bool) int64 {
+	if double {
+		return rxDDSectorBytes
+	}
+	return rxSDSectorBytes
+}
+
+// AttachImage attaches the disk image to drive unit.  double is set
+// for a double density disk, which only an RX28 can use.
+func (rx *RX8E) AttachImage(unit int, image DiskImage, double bool, writeProtect bool) error {
+	if unit < 0 || unit >= rxDrives {
+		return fmt.Errorf("%s: invalid unit: %d", rx.name(), unit)
+	}
+	if double && !rx.rx02 {
+		return fmt.Errorf("%s: double density not supported", rx.name())
+	}
+	if err := rx.DetachImage(unit); err != nil {
+		return err
+	}
+	rx.drives[unit] = rxDrive{
+		image:        image,
+		double:       double,
+		writeProtect: writeProtect,
+	}
+	return nil
+}
+
+// OpenImage opens the image file filename and attaches it to drive
+// unit.  An RX28 uses the image as a double density disk unless it is
+// the size of a single density one.  If the file doesn't exist it is
+// created unless writeProtect is set.  The file is closed when
+// detached.  Deleted data marks are loaded from filename with .del
+// added if it exists.  This file is only created once a sector is
+// written with a deleted data mark and is removed once no sectors have
+// one, so that the image itself stays in SIMH format.
+func (rx *RX8E) OpenImage(unit int, filename string, writeProtect bool) error {
+	f, err := openImage(filename, writeProtect)
+	if err != nil {
+		return fmt.Errorf("%s: %w", rx.name(), err)
+	}
+	double := false
+	if fi, err := f.Stat(); err == nil && rx.rx02 {
+		double = fi.Size() != RXImageSize(false)
+	}
+	if err := rx.AttachImage(unit, f, double, writeProtect); err != nil {
+		f.Close()
+		return err
+	}
+	d := &rx.drives[unit]
+	d.file = f
+	d.marksFile = filename + rxMarksSuffix
+	if err := d.loadMarks(); err != nil {
+		rx.DetachImage(unit)
+		return fmt.Errorf("%s: %w", rx.name(), err)
+	}
+	return nil
+}
+
+// DetachImage detaches the disk image from drive unit
+func (rx *RX8E) DetachImage(unit int) error {
+	if unit < 0 || unit >= rxDrives {
+		return fmt.Errorf("%s: invalid unit: %d", rx.name(), unit)
+	}
+	var err error
+	if f := rx.drives[unit].file; f != nil {
+		err = f.Close()
+	}
+	rx.drives[unit] = rxDrive{}
+	return err
+}
+
+// SetWriteProtect sets whether the disk in drive unit is write
+// protected
+func (rx *RX8E) SetWriteProtect(unit int, on bool) {
+	if unit >= 0 && unit < rxDrives {
+		rx.drives[unit].writeProtect = on
+	}
+}
+
+// Attach also cancels any function in progress
+func (rx *RX8E) Attach(p *PDP8) {
+	rx.p = p
+	rx.state = rxIdle
+	rx.pending.cancel()
+}
+
+// Closes any image files opened by OpenImage
+func (rx *RX8E) Close() error {
+	var errs []error
+	for unit := range rx.drives {
+		if err := rx.DetachImage(unit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (rx *RX8E) DeviceNumbers() []int {
+	return []int{0o75}
+}
+
+func (rx *RX8E) Info() DeviceInfo {
+	if rx.rx02 {
+		return DeviceInfo{
+			Name:        "RX28",
+			Description: "RX02 floppy disk controller",
+		}
+	}
+	return DeviceInfo{
+		Name:        "RX8E",
+		Description: "RX01 floppy disk controller",
+	}
+}
+
+// Reset initializes the controller
+func (rx *RX8E) Reset() {
+	rx.CAF()
+}
+
+// CAF initializes the controller
+func (rx *RX8E) CAF() {
+	rx.initialize()
+}
+
+// Interrupt returns if done is set and interrupts are enabled
+func (rx *RX8E) Interrupt() (bool, error) {
+	return rx.done && rx.ie, nil
+}
+
+// IOT returns PC, LAC, error
+func (rx *RX8E) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	link := lac & 0o10000
+	ac := mask(lac)
+	switch ir & 0o7 {
+	case 0o1: // LCD - Load command
+		if rx.state == rxIdle {
+			rx.csr = ac
+			if !rx.rx02 {
+				rx.csr &^= rxcsDouble
+			}
+			rx.start()
+		}
+		ac = 0
+	case 0o2: // XDR - Transfer data register
+		ac = rx.transferData(ac)
+	case 0o3: // STR - Skip on transfer request
+		if rx.tr {
+			rx.tr = false
+			pc = mask(pc + 1)
+		}
+	case 0o4: // SER - Skip on error
+		if rx.err {
+			rx.err = false
+			pc = mask(pc + 1)
+		}
+	case 0o5: // SDN - Skip on done
+		if rx.done {
+			rx.done = false
+			pc = mask(pc + 1)
+		}
+	case 0o6: // INTR - Load interrupt enable
+		rx.ie = (ac & 0o1) == 0o1
+	case 0o7: // INIT - Initialize
+		rx.initialize()
+	}
+	return pc, link | ac, nil
+}
+
+// name returns the name of the controller for errors
+func (rx *RX8E) name() string {
+	return rx.Info().Name
+}
+
+// drive returns the drive selected by the command register
+func (rx *RX8E) drive() *rxDrive {
+	return &rx.drives[(rx.csr&rxcsDrive)>>4]
+}
+
+// function returns the function in the command register
+func (rx *RX8E) function() uint {
+	return (rx.csr & rxcsFunction) >> 1
+}
+
+// eightBit returns whether the command register selects 8-bit mode
+func (rx *RX8E) eightBit() bool {
+	return (rx.csr & rxcs8Bit) != 0
+}
+
+// double returns whether the command register selects double density
+func (rx *RX8E) double() bool {
+	return (rx.csr & rxcsDouble) != 0
+}
+
+// bufLen returns the number of words, or bytes in 8-bit mode, of the
+// sector buffer transferred by Fill Buffer and Empty Buffer
+func (rx *RX8E) bufLen() uint {
+	n := uint(rxSectorBytes(rx.double()))
+	if rx.eightBit() {
+		return n
+	}
+	return n / 2
+}
+
+// status returns the error and status register
+func (rx *RX8E) status() uint {
+	s := rx.es
+	d := rx.drive()
+	if rx.rx02 {
+		s |= rxesRX02
+		if d.double {
+			s |= rxesDouble
+		}
+	}
+	if d.image != nil {
+		s |= rxesReady
+	}
+	return s
+}
+
+// after calls fn once cycles have passed unless the function is
+// cancelled first
+func (rx *RX8E) after(cycles uint64, fn func() error) {
+	rx.pending.cancel()
+	rx.pending = rx.p.schedule(rx, attachedCycles(rx.p)+cycles, fn)
+}
+
+// requestTransfer sets the transfer request flag once the interface
+// is ready for the next XDR
+func (rx *RX8E) requestTransfer() {
+	rx.after(rxTransferCycles, func() error {
+		rx.tr = true
+		return nil
+	})
+}
+
+// finish finishes the function with the error code errCode, or 0 if
+// none, and sets done.  The interface register is left holding the
+// error and status register.
+func (rx *RX8E) finish(errCode uint) {
+	rx.state = rxIdle
+	rx.tr = false
+	rx.errCode = errCode
+	if errCode != 0 {
+		rx.err = true
+	}
+	rx.dbr = rx.status()
+	rx.done = true
+}
+
+// initialize cancels any function, returns the heads to track 0 and
+// then reads sector 1 of track 1 of drive 0 into the sector buffer
+func (rx *RX8E) initialize() {
+	rx.csr = 0
+	rx.dbr = 0
+	rx.es = 0
+	rx.tr = false
+	rx.done = false
+	rx.err = false
+	rx.ie = false
+	rx.state = rxIdle
+	rx.pending.cancel()
+	if rx.p == nil {
+		return
+	}
+	rx.state = rxBusy
+	d := rx.drive()
+	// Step out to track 0, in to track 1 and wait for sector 1 to pass
+	cycles := uint64(d.track+1)*rxTrackCycles + rxSettleCycles + rxRevCycles
+	for i := range rx.drives {
+		rx.drives[i].track = 0
+	}
+	rx.track = 1
+	rx.sector = 1
+	rx.after(cycles, func() error {
+		d.track = 1
+		if d.image != nil {
+			if err := rx.readSector(d); err != nil {
+				return err
+			}
+		}
+		rx.es = rxesInitDone
+		rx.finish(0)
+		return nil
+	})
+}
+
+// start starts the function in the command register
+func (rx *RX8E) start() {
+	rx.es = 0
+	rx.tr = false
+	rx.done = false
+	rx.err = false
+	switch rx.function() {
+	case rxFill:
+		rx.buf = [rxDDSectorBytes]byte{}
+		rx.bptr = 0
+		rx.state = rxFilling
+		rx.requestTransfer()
+	case rxEmpty:
+		rx.bptr = 0
+		rx.dbr = rx.bufWord(0)
+		rx.state = rxEmptying
+		rx.requestTransfer()
+	case rxWrite, rxRead, rxWriteDeleted:
+		rx.state = rxWantSector
+		rx.requestTransfer()
+	case rxSetDensity:
+		if !rx.rx02 {
+			rx.finish(0)
+			return
+		}
+		rx.state = rxWantKey
+		rx.requestTransfer()
+	case rxReadStatus:
+		rx.finish(0)
+	case rxReadError:
+		errCode := rx.errCode
+		rx.finish(0)
+		rx.errCode = errCode
+		rx.dbr = errCode
+	}
+}
+
+// transferData carries out XDR and returns the new AC
+func (rx *RX8E) transferData(ac uint) uint {
+	in := ac
+	if rx.eightBit() {
+		in &= 0o377
+	}
+	switch rx.state {
+	case rxFilling:
+		rx.tr = false
+		rx.setBufWord(rx.bptr, in)
+		rx.bptr++
+		if rx.bptr >= rx.bufLen() {
+			rx.finish(0)
+		} else {
+			rx.requestTransfer()
+		}
+		return ac
+	case rxEmptying:
+		rx.tr = false
+		out := rx.dbr
+		rx.bptr++
+		if rx.bptr >= rx.bufLen() {
+			rx.finish(0)
+		} else {
+			rx.dbr = rx.bufWord(rx.bptr)
+			rx.requestTransfer()
+		}
+		return rx.toAC(ac, out)
+	case rxWantSector:
+		rx.tr = false
+		rx.sector = in & 0o177
+		rx.state = rxWantTrack
+		rx.requestTransfer()
+		return ac
+	case rxWantTrack:
+		rx.tr = false
+		rx.track = in & 0o377
+		rx.state = rxBusy
+		rx.startTransfer()
+		return ac
+	case rxWantKey:
+		rx.tr = false
+		if (in & 0o377) != rxKey {
+			rx.finish(rxErrKey)
+			return ac
+		}
+		rx.state = rxBusy
+		rx.startSetDensity()
+		return ac
+	}
+	return rx.toAC(ac, rx.dbr)
+}
+
+// toAC returns the AC after reading v from the interface register,
+// which leaves the top 4 bits of the AC alone in 8-bit mode
+func (rx *RX8E) toAC(ac uint, v uint) uint {
+	if rx.eightBit() {
+		return ac&0o7400 | v&0o377
+	}
+	return v
+}
+
+// bufWord returns word, or byte in 8-bit mode, i of the sector buffer.
+// In 12-bit mode each pair of words is packed into three bytes.
+func (rx *RX8E) bufWord(i uint) uint {
+	if rx.eightBit() {
+		return uint(rx.buf[i])
+	}
+	b := (i / 2) * 3
+	if i%2 == 0 {
+		return uint(rx.buf[b])<<4 | uint(rx.buf[b+1])>>4
+	}
+	return uint(rx.buf[b+1]&0o17)<<8 | uint(rx.buf[b+2])
+}
+
+// setBufWord sets word, or byte in 8-bit mode, i of the sector buffer
+func (rx *RX8E) setBufWord(i uint, w uint) {
+	if rx.eightBit() {
+		rx.buf[i] = byte(w)
+		return
+	}
+	b := (i / 2) * 3
+	if i%2 == 0 {
+		rx.buf[b] = byte(w >> 4)
+		rx.buf[b+1] = rx.buf[b+1]&0o17 | byte(w&0o17)<<4
+	} else {
+		rx.buf[b+1] = rx.buf[b+1]&0o360 | byte(w>>8)&0o17
+		rx.buf[b+2] = byte(w)
+	}
+}
+
+// seekCycles returns how many cycles it takes d to seek to track
+func (rx *RX8E) seekCycles(d *rxDrive, track uint) uint64 {
+	if track == d.track {
+		return 0
+	}
+	moved := int(track) - int(d.track)
+	if moved < 0 {
+		moved = -moved
+	}
+	return uint64(moved)*rxTrackCycles + rxSettleCycles
+}
+
+// startTransfer seeks to the track and then reads or writes the
+// sector once it has turned to it
+func (rx *RX8E) startTransfer() {
+	d := rx.drive()
+	write := rx.function() != rxRead
+	switch {
+	case d.image == nil:
+		rx.finish(rxErrNotReady)
+		return
+	case rx.rx02 && rx.double() != d.double:
+		rx.es |= rxesDensityErr
+		rx.finish(rxErrDensity)
+		return
+	case write && d.writeProtect:
+		rx.finish(rxErrWriteProtect)
+		return
+	case rx.track >= rxTracks:
+		rx.finish(rxErrTrack)
+		return
+	case rx.sector < 1 || rx.sector > rxSectors:
+		// The controller gives up after two revolutions
+		rx.after(rx.seekCycles(d, rx.track)+2*rxRevCycles, func() error {
+			rx.finish(rxErrSector)
+			return nil
+		})
+		d.track = rx.track
+		return
+	}
+
+	cycles := rx.seekCycles(d, rx.track)
+	at := uint64(attachedCycles(rx.p)+cycles) / rxSectorCycles % rxSectors
+	wait := (uint64(rx.sector-1) + rxSectors - at) % rxSectors
+	cycles += (wait + 1) * rxSectorCycles
+	d.track = rx.track
+	deleted := rx.function() == rxWriteDeleted
+	rx.after(cycles, func() error {
+		if d.image == nil { // Detached while busy
+			rx.finish(rxErrNotReady)
+			return nil
+		}
+		var err error
+		if write {
+			err = rx.writeSector(d, deleted)
+		} else {
+			err = rx.readSector(d)
+		}
+		rx.finish(0)
+		return err
+	})
+}
+
+// sectorPos returns the position in the image of d of the sector
+// addressed and its index
+func (rx *RX8E) sectorPos(d *rxDrive) (int64, uint) {
+	n := rx.track*rxSectors + rx.sector - 1
+	return int64(n) * rxSectorBytes(d.double), n
+}
+
+// readSector reads the sector addressed into the sector buffer
+func (rx *RX8E) readSector(d *rxDrive) error {
+	pos, n := rx.sectorPos(d)
+	rx.buf = [rxDDSectorBytes]byte{}
+	buf := rx.buf[:rxSectorBytes(d.double)]
+	if _, err := d.image.ReadAt(buf, pos); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", rx.name(), err)
+	}
+	if d.deleted[n] {
+		rx.es |= rxesDeleted
+	}
+	return nil
+}
+
+// writeSector writes the sector buffer to the sector addressed
+func (rx *RX8E) writeSector(d *rxDrive, deleted bool) error {
+	pos, n := rx.sectorPos(d)
+	if _, err := d.image.WriteAt(rx.buf[:rxSectorBytes(d.double)], pos); err != nil {
+		return fmt.Errorf("%s: %w", rx.name(), err)
+	}
+	if d.deleted[n] == deleted {
+		return nil
+	}
+	if d.deleted == nil {
+		d.deleted = map[uint]bool{}
+	}
+	d.deleted[n] = deleted
+	if err := d.saveMarks(); err != nil {
+		return fmt.Errorf("%s: %w", rx.name(), err)
+	}
+	return nil
+}
+
+// loadMarks loads the deleted data marks from the marks file if it
+// exists
+func (d *rxDrive) loadMarks() error {
+	marks, err := os.ReadFile(d.marksFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	d.deleted = map[uint]bool{}
+	for n, m := range marks {
+		if m != 0 {
+			d.deleted[uint(n)] = true
+		}
+	}
+	return nil
+}
+
+// saveMarks saves the deleted data marks to the marks file, if there
+// is one.  The file is removed if no sectors have a mark.
+func (d *rxDrive) saveMarks() error {
+	if d.marksFile == "" {
+		return nil
+	}
+	marks := make([]byte, rxTracks*rxSectors)
+	marked := false
+	for n, deleted := range d.deleted {
+		if deleted {
+			marks[n] = 1
+			marked = true
+		}
+	}
+	if !marked {
+		err := os.Remove(d.marksFile)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(d.marksFile, marks, 0o644)
+}
+
+// startSetDensity reformats the disk to the density in the command
+// register, which clears every sector
+func (rx *RX8E) startSetDensity() {
+	d := rx.drive()
+	if d.image == nil {
+		rx.finish(rxErrNotReady)
+		return
+	}
+	if d.writeProtect {
+		rx.finish(rxErrWriteProtect)
+		return
+	}
+	double := rx.double()
+	cycles := rx.seekCycles(d, 0) + rxFormatCycles
+	d.track = 0
+	rx.after(cycles, func() error {
+		if d.image == nil { // Detached while busy
+			rx.finish(rxErrNotReady)
+			return nil
+		}
+		size := RXImageSize(double)
+		var err error
+		if d.file != nil {
+			// Clear the file and change its size
+			if err = d.file.Truncate(0); err == nil {
+				err = d.file.Truncate(size)
+			}
+		} else {
+			_, err = d.image.WriteAt(make([]byte, size), 0)
+		}
+		d.double = double
+		if len(d.deleted) != 0 {
+			d.deleted = nil
+			if serr := d.saveMarks(); err == nil {
+				err = serr
+			}
+		}
+		rx.finish(0)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rx.name(), err)
+		}
+		return nil
+	})
+}
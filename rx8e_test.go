@@ -0,0 +1,406 @@
+package pdp8
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newRXMachine returns a machine with rx which loops at 0200
+func newRXMachine(t *testing.T, rx *RX8E) *PDP8 {
+	t.Helper()
+	p, err := New(WithDevice(rx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mem[0o200] = 0o5200 // JMP 200
+	p.pc = 0o200
+	return p
+}
+
+// newRXImage returns the name of an image file holding image
+func newRXImage(t *testing.T, image []byte) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.rx01")
+	if err := os.WriteFile(filename, image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// rxIOT executes the IOT with ac and returns whether it skipped and
+// the resulting AC
+func rxIOT(t *testing.T, rx *RX8E, ir uint, ac uint) (bool, uint) {
+	t.Helper()
+	pc, lac, err := rx.IOT(ir, 0o200, ac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc == 0o201, mask(lac)
+}
+
+// rxWait runs the machine until the skip IOT ir skips
+func rxWait(t *testing.T, p *PDP8, rx *RX8E, ir uint) {
+	t.Helper()
+	for i := 0; i < 1000000; i++ {
+		if skip, _ := rxIOT(t, rx, ir, 0); skip {
+			return
+		}
+		if _, _, err := p.Run(20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatalf("timeout waiting for IOT: %04o", ir)
+}
+
+// rxFillBuffer fills the sector buffer with words using the command cmd
+func rxFillBuffer(t *testing.T, p *PDP8, rx *RX8E, cmd uint, words []uint) {
+	t.Helper()
+	rxIOT(t, rx, 0o6751, cmd|rxFill<<1) // LCD
+	for _, w := range words {
+		rxWait(t, p, rx, 0o6753) // STR
+		rxIOT(t, rx, 0o6752, w)  // XDR
+	}
+	rxWait(t, p, rx, 0o6755) // SDN
+}
+
+// rxEmptyBuffer returns n words emptied from the sector buffer using the
+// command cmd
+func rxEmptyBuffer(t *testing.T, p *PDP8, rx *RX8E, cmd uint, n int) []uint {
+	t.Helper()
+	words := make([]uint, n)
+	rxIOT(t, rx, 0o6751, cmd|rxEmpty<<1) // LCD
+	for i := range words {
+		rxWait(t, p, rx, 0o6753)                   // STR
+		_, words[i] = rxIOT(t, rx, 0o6752, 0o7000) // XDR
+	}
+	rxWait(t, p, rx, 0o6755) // SDN
+	return words
+}
+
+// rxSector carries out the function in cmd on the sector and track
+// and returns whether there was an error and the interface register
+func rxSector(t *testing.T, p *PDP8, rx *RX8E, cmd uint, sector uint, track uint) (bool, uint) {
+	t.Helper()
+	rxIOT(t, rx, 0o6751, cmd) // LCD
+	rxWait(t, p, rx, 0o6753)  // STR
+	rxIOT(t, rx, 0o6752, sector)
+	rxWait(t, p, rx, 0o6753) // STR
+	rxIOT(t, rx, 0o6752, track)
+	rxWait(t, p, rx, 0o6755)            // SDN
+	isErr, _ := rxIOT(t, rx, 0o6754, 0) // SER
+	_, dbr := rxIOT(t, rx, 0o6752, 0)   // XDR
+	return isErr, dbr
+}
+
+func TestRX8E_12bit_write_read(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rx01")
+	rx := NewRX8E()
+	defer rx.Close()
+	if err := rx.OpenImage(1, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	p := newRXMachine(t, rx)
+	const drive = rxcsDrive
+
+	words := make([]uint, 64)
+	for i := range words {
+		words[i] = 0o7000 + uint(i)
+	}
+	rxFillBuffer(t, p, rx, drive, words)
+	if isErr, _ := rxSector(t, p, rx, drive|rxWrite<<1, 7, 5); isErr {
+		t.Fatalf("write error: %04o", rx.errCode)
+	}
+
+	// Each pair of words is packed into three bytes
+	buf := make([]byte, rxSDSectorBytes)
+	if _, err := rx.drives[1].image.ReadAt(buf, (5*rxSectors+6)*rxSDSectorBytes); err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != 0o340 || buf[1] != 0o16 || buf[2] != 0o1 || buf[96] != 0 {
+		t.Errorf("got sector bytes: %03o", buf[:4])
+	}
+
+	rxFillBuffer(t, p, rx, drive, make([]uint, 64))
+	isErr, status := rxSector(t, p, rx, drive|rxRead<<1, 7, 5)
+	if isErr || status != rxesReady {
+		t.Fatalf("read - got error: %v, status: %04o", isErr, status)
+	}
+	got := rxEmptyBuffer(t, p, rx, drive, 64)
+	for i, w := range got {
+		if w != words[i] {
+			t.Fatalf("word %d got: %04o, want: %04o", i, w, words[i])
+		}
+	}
+}
+
+func TestRX28_8bit_double_density(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rx02")
+	rx := NewRX28()
+	defer rx.Close()
+	if err := rx.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	p := newRXMachine(t, rx)
+	const cmd = rxcs8Bit | rxcsDouble
+
+	bytes := make([]uint, rxDDSectorBytes)
+	for i := range bytes {
+		bytes[i] = uint(i) ^ 0o125
+	}
+	rxFillBuffer(t, p, rx, cmd, bytes)
+	if isErr, _ := rxSector(t, p, rx, cmd|rxWriteDeleted<<1, 26, 76); isErr {
+		t.Fatalf("write error: %04o", rx.errCode)
+	}
+	rxFillBuffer(t, p, rx, cmd, make([]uint, rxDDSectorBytes))
+	isErr, status := rxSector(t, p, rx, cmd|rxRead<<1, 26, 76)
+	want := uint(rxesReady | rxesDeleted | rxesRX02 | rxesDouble)
+	if isErr || status != want {
+		t.Fatalf("read - got error: %v, status: %04o, want: %04o", isErr, status, want)
+	}
+	got := rxEmptyBuffer(t, p, rx, cmd, rxDDSectorBytes)
+	for i, b := range got {
+		// The top 4 bits of the AC are left alone
+		if want := 0o7000 | bytes[i]; b != want {
+			t.Fatalf("byte %d got: %04o, want: %04o", i, b, want)
+		}
+	}
+
+	// A normal write clears the deleted data mark
+	if isErr, _ := rxSector(t, p, rx, cmd|rxWrite<<1, 26, 76); isErr {
+		t.Fatalf("write error: %04o", rx.errCode)
+	}
+	if _, status := rxSector(t, p, rx, cmd|rxRead<<1, 26, 76); (status & rxesDeleted) != 0 {
+		t.Errorf("deleted data mark not cleared")
+	}
+}
+
+func TestRX8E_deleted_marks_saved(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.rx01")
+	rx := NewRX8E()
+	if err := rx.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	p := newRXMachine(t, rx)
+	rxFillBuffer(t, p, rx, 0, make([]uint, 64))
+	for _, track := range []uint{3, 4} {
+		if isErr, _ := rxSector(t, p, rx, rxWriteDeleted<<1, 2, track); isErr {
+			t.Fatalf("write error: %04o", rx.errCode)
+		}
+	}
+	if isErr, _ := rxSector(t, p, rx, rxWrite<<1, 2, 4); isErr {
+		t.Fatalf("write error: %04o", rx.errCode)
+	}
+	if err := rx.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The marks are kept when the image is opened again
+	rx = NewRX8E()
+	defer rx.Close()
+	if err := rx.OpenImage(0, filename, true); err != nil {
+		t.Fatal(err)
+	}
+	p = newRXMachine(t, rx)
+	cases := []struct {
+		track uint
+		want  uint
+	}{
+		{track: 3, want: rxesReady | rxesDeleted},
+		{track: 4, want: rxesReady},
+	}
+	for _, c := range cases {
+		isErr, status := rxSector(t, p, rx, rxRead<<1, 2, c.track)
+		if isErr || status != c.want {
+			t.Errorf("track: %d - got error: %v, status: %04o, want: %04o",
+				c.track, isErr, status, c.want)
+		}
+	}
+}
+
+func TestRX8E_deleted_marks_file(t *testing.T) {
+	filename := newRXImage(t, make([]byte, RXImageSize(false)))
+	marksFile := filename + rxMarksSuffix
+	exists := func() bool {
+		_, err := os.Stat(marksFile)
+		return err == nil
+	}
+
+	// Opening, reading and writing without marks creates no file
+	for _, writeProtect := range []bool{true, false} {
+		rx := NewRX8E()
+		if err := rx.OpenImage(0, filename, writeProtect); err != nil {
+			t.Fatal(err)
+		}
+		p := newRXMachine(t, rx)
+		rxSector(t, p, rx, rxRead<<1, 1, 0)
+		if !writeProtect {
+			rxSector(t, p, rx, rxWrite<<1, 1, 0)
+		}
+		if err := rx.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if exists() {
+			t.Fatalf("write protected: %t - marks file created", writeProtect)
+		}
+	}
+
+	// The file is created by a mark and removed once there are none
+	rx := NewRX8E()
+	defer rx.Close()
+	if err := rx.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	p := newRXMachine(t, rx)
+	rxSector(t, p, rx, rxWriteDeleted<<1, 1, 0)
+	if !exists() {
+		t.Fatal("marks file not created")
+	}
+	rxSector(t, p, rx, rxWrite<<1, 1, 0)
+	if exists() {
+		t.Error("marks file not removed")
+	}
+}
+
+func TestRX8E_errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		rx02   bool
+		drive  uint
+		cmd    uint
+		sector uint
+		track  uint
+		want   uint
+	}{
+		{name: "no disk", drive: rxcsDrive, cmd: rxRead << 1, sector: 1, want: rxErrNotReady},
+		{name: "track", cmd: rxRead << 1, sector: 1, track: 77, want: rxErrTrack},
+		{name: "sector", cmd: rxRead << 1, sector: 27, want: rxErrSector},
+		{name: "write protected", cmd: rxWrite << 1, sector: 1, want: rxErrWriteProtect},
+		{name: "density", rx02: true, cmd: rxRead<<1 | rxcsDouble, sector: 1, want: rxErrDensity},
+	}
+	filename := newRXImage(t, make([]byte, RXImageSize(false)))
+	for _, c := range cases {
+		rx := NewRX8E()
+		if c.rx02 {
+			rx = NewRX28()
+		}
+		if err := rx.OpenImage(0, filename, true); err != nil {
+			t.Fatal(err)
+		}
+		p := newRXMachine(t, rx)
+		isErr, _ := rxSector(t, p, rx, c.drive|c.cmd, c.sector, c.track)
+		rxIOT(t, rx, 0o6751, rxReadError<<1)  // LCD
+		rxWait(t, p, rx, 0o6755)              // SDN
+		_, errCode := rxIOT(t, rx, 0o6752, 0) // XDR
+		if !isErr || errCode != c.want {
+			t.Errorf("%s - got error: %v, error code: %04o, want: %04o",
+				c.name, isErr, errCode, c.want)
+		}
+		if err := rx.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRX28_set_density(t *testing.T) {
+	filename := newRXImage(t, make([]byte, RXImageSize(false)))
+	rx := NewRX28()
+	defer rx.Close()
+	if err := rx.OpenImage(0, filename, false); err != nil {
+		t.Fatal(err)
+	}
+	p := newRXMachine(t, rx)
+
+	// The wrong key
+	rxIOT(t, rx, 0o6751, rxcsDouble|rxSetDensity<<1) // LCD
+	rxWait(t, p, rx, 0o6753)                         // STR
+	rxIOT(t, rx, 0o6752, 0o110)                      // XDR
+	rxWait(t, p, rx, 0o6755)                         // SDN
+	if rx.errCode != rxErrKey || rx.drives[0].double {
+		t.Fatalf("wrong key - got error code: %04o", rx.errCode)
+	}
+
+	rxIOT(t, rx, 0o6751, rxcsDouble|rxSetDensity<<1) // LCD
+	rxWait(t, p, rx, 0o6753)                         // STR
+	rxIOT(t, rx, 0o6752, rxKey)                      // XDR
+	rxWait(t, p, rx, 0o6755)                         // SDN
+	if isErr, _ := rxIOT(t, rx, 0o6754, 0); isErr {  // SER
+		t.Fatalf("got error code: %04o", rx.errCode)
+	}
+	if p.Cycles() < rxFormatCycles {
+		t.Errorf("got cycles: %d, want at least: %d", p.Cycles(), rxFormatCycles)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rx.drives[0].double || fi.Size() != RXImageSize(true) {
+		t.Errorf("got double density: %v, image size: %d",
+			rx.drives[0].double, fi.Size())
+	}
+}
+
+func TestRX8E_initialize(t *testing.T) {
+	image := make([]byte, RXImageSize(false))
+	image[rxSectors*rxSDSectorBytes] = 0o123
+	rx := NewRX8E()
+	defer rx.Close()
+	if err := rx.OpenImage(0, newRXImage(t, image), false); err != nil {
+		t.Fatal(err)
+	}
+	p := newRXMachine(t, rx)
+	rxIOT(t, rx, 0o6757, 0)  // INIT
+	rxWait(t, p, rx, 0o6755) // SDN
+	if _, status := rxIOT(t, rx, 0o6752, 0); status != rxesInitDone|rxesReady {
+		t.Errorf("got status: %04o", status)
+	}
+	if got := rxEmptyBuffer(t, p, rx, rxcs8Bit, rxSDSectorBytes); got[0] != 0o7123 {
+		t.Errorf("got first byte of track 1 sector 1: %04o", got[0])
+	}
+}
+
+func TestRX8E_interrupt(t *testing.T) {
+	rx := NewRX8E()
+	p := newRXMachine(t, rx)
+	routine := map[uint]uint{
+		0o200: 0o1220, // TAD 220
+		0o201: 0o6751, // LCD
+		0o202: 0o7001, // IAC
+		0o203: 0o6756, // INTR
+		0o204: 0o6001, // ION
+		0o205: 0o5205, // JMP 205
+		0o220: rxReadStatus << 1,
+		0o1:   0o7402, // HLT
+	}
+	for addr, v := range routine {
+		p.mem[addr] = v
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(100)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o2 {
+		t.Errorf("interrupt not taken, PC: %04o", p.pc)
+	}
+}
+
+func TestRXLogicalSector(t *testing.T) {
+	cases := []struct {
+		n      int
+		track  int
+		sector int
+	}{
+		{n: 0, track: 1, sector: 1},
+		{n: 1, track: 1, sector: 3},
+		{n: 12, track: 1, sector: 25},
+		{n: 13, track: 1, sector: 2},
+		{n: 25, track: 1, sector: 26},
+		{n: 26, track: 2, sector: 7},
+	}
+	for _, c := range cases {
+		track, sector := RXLogicalSector(c.n)
+		if track != c.track || sector != c.sector {
+			t.Errorf("RXLogicalSector(%d) got: %d/%d, want: %d/%d",
+				c.n, track, sector, c.track, c.sector)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+/*
+ * A TC08 DECtape controller with up to eight TU56 drives
+ *
+ * The controller is devices 76 and 77.  A tape has 1474 blocks of
+ * either 129 words, as used by OS/8, or 128 words.  Images are in
+ * SIMH format with each word in a 16-bit little endian word.  The
+ * motion of each tape is simulated a block at a time, including the
+ * time taken to get up to speed or turn around, so that searching
+ * takes as long as it would.  Data is transferred using three cycle
+ * data breaks with the word count at 7754 and the current address at
+ * 7755.  Read All and Write All also transfer the six words before
+ * and after the data of each block, which are the mark track words
+ * between the block marks laid out as for the TD8E and taken 12 bits
+ * at a time.  These hold the checksum and reverse checksum, worked out
+ * from the data, and are ignored when written.  Tapes read or written
+ * in reverse have their words in reverse order and obverse
+ * complemented.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	dtUnits    = 8
+	dtBlocks   = 1474
+	dtWCAddr   = 0o7754 // The word count, followed by the current address
+	dtOverhead = 15     // Word times of each block used by headers and gaps
+	dtAllWords = 6      // Words before and after the data used by Read All and Write All
+)
+
+// Timings in cycles
+const (
+	dtWordCycles  = instructionsPerSecond * 133 / 1000000 // 133µs to pass a word
+	dtAccelCycles = instructionsPerSecond * 150 / 1000    // 150ms to get up to speed
+)
+
+// Status register A
+const (
+	dtaUnit      = 0o7000 // The unit selected
+	dtaReverse   = 0o400  // Move in reverse
+	dtaGo        = 0o200  // Move the tape
+	dtaFunction  = 0o70   // The function
+	dtaIntEnable = 0o4    // Interrupt on the DECtape or error flags
+	dtaKeepErr   = 0o2    // If 0 DTXA clears the error flags
+	dtaKeepFlag  = 0o1    // If 0 DTXA clears the DECtape flag
+)
+
+// Functions in status register A
+const (
+	dtMove     = 0
+	dtSearch   = 1
+	dtRead     = 2
+	dtReadAll  = 3
+	dtWrite    = 4
+	dtWriteAll = 5
+)
+
+// Status register B
+const (
+	dtbError     = 0o4000 // Any error
+	dtbMarkTrack = 0o2000 // Mark track error, not emulated
+	dtbEndOfTape = 0o1000 // End of tape reached
+	dtbSelect    = 0o400  // No tape or write to a write protected tape
+	dtbParity    = 0o200  // Parity error, not emulated
+	dtbTiming    = 0o100  // Timing error, not emulated
+	dtbField     = 0o70   // The memory field
+	dtbFlag      = 0o1    // DECtape flag
+
+	dtbErrors = dtbMarkTrack | dtbEndOfTape | dtbSelect | dtbParity | dtbTiming
+)
+
+type TC08 struct {
+	p          *PDP8 // The machine attached to
+	units      [dtUnits]dtUnit
+	sta        uint   // Status register A
+	stb        uint   // Status register B, without the error bit
+	wcOverflow bool   // The word count has overflowed for this function
+	pending    *event // The next block to be reached, nil if stopped
+}
+
+// dtUnit is a TU56 drive
+type dtUnit struct {
+	image        DiskImage
+	file         *os.File // The file opened by OpenImage, if any
+	blockWords   uint
+	writeProtect bool
+	pos          int    // The next block forward, the next in reverse is pos-1
+	moving       bool   // Whether the tape is moving
+	reverse      bool   // Whether the tape is moving in reverse
+	nextAt       uint64 // The cycle that the next block is reached when moving
+}
+
+// NewTC08 returns a controller with no tapes attached
+func NewTC08() *TC08 {
+	return &TC08{}
+}
+
+// AttachImage attaches the tape image to drive unit.  blockWords is
+// the number of words in each block, 129 or 128.
+func (dt *TC08) AttachImage(unit int, image DiskImage, blockWords int, writeProtect bool) error {
+	if unit < 0 || unit >= dtUnits {
+		return fmt.Errorf("TC08: invalid unit: %d", unit)
+	}
+	if blockWords != 129 && blockWords != 128 {
+		return fmt.Errorf("TC08: invalid words per block: %d", blockWords)
+	}
+	if err := dt.DetachImage(unit); err != nil {
+		return err
+	}
+	dt.units[unit] = dtUnit{
+		image:        image,
+		blockWords:   uint(blockWords),
+		writeProtect: writeProtect,
+	}
+	return nil
+}
+
+// OpenImage opens the tape image file filename and attaches it to
+// drive unit.  If the file is the size of a tape with 128 word blocks
+// it is used as one, otherwise it is taken to have 129 word blocks.
+// If the file doesn't exist it is created unless writeProtect is set.
+// The file is closed when detached.
+func (dt *TC08) OpenImage(unit int, filename string, writeProtect bool) error {
+	f, err := openImage(filename, writeProtect)
+	if err != nil {
+		return fmt.Errorf("TC08: %w", err)
+	}
+	if err := dt.AttachImage(unit, f, dtImageBlockWords(f), writeProtect); err != nil {
+		f.Close()
+		return err
+	}
+	dt.units[unit].file = f
+	return nil
+}
+
+// dtImageBlockWords returns the number of words in each block of a
+// tape image file.  This is 128 if the file is the size of a tape with
+// 128 word blocks, otherwise 129.
+func dtImageBlockWords(f *os.File) int {
+	if fi, err := f.Stat(); err == nil && fi.Size() == dtBlocks*128*2 {
+		return 128
+	}
+	return 129
+}
+
+// DetachImage detaches the tape image from drive unit
+func (dt *TC08) DetachImage(unit int) error {
+	if unit < 0 || unit >= dtUnits {
+		return fmt.Errorf("TC08: invalid unit: %d", unit)
+	}
+	var err error
+	if f := dt.units[unit].file; f != nil {
+		err = f.Close()
+	}
+	dt.units[unit] = dtUnit{}
+	return err
+}
+
+// SetWriteProtect sets the write lock switch of drive unit
+func (dt *TC08) SetWriteProtect(unit int, on bool) {
+	if unit >= 0 && unit < dtUnits {
+		dt.units[unit].writeProtect = on
+	}
+}
+
+// Attach also stops the tapes
+func (dt *TC08) Attach(p *PDP8) {
+	dt.p = p
+	dt.stop()
+}
+
+// Closes any image files opened by OpenImage
+func (dt *TC08) Close() error {
+	var errs []error
+	for unit := range dt.units {
+		if err := dt.DetachImage(unit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (dt *TC08) DeviceNumbers() []int {
+	return []int{0o76, 0o77}
+}
+
+func (dt *TC08) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "TC08",
+		Description: "TU56 DECtape controller",
+	}
+}
+
+// Reset clears the controller and stops the tapes
+func (dt *TC08) Reset() {
+	dt.CAF()
+}
+
+// CAF clears the status registers and stops the tapes
+func (dt *TC08) CAF() {
+	dt.sta = 0
+	dt.stb = 0
+	dt.stop()
+}
+
+// Interrupt returns if the DECtape flag or an error flag is set and
+// interrupts are enabled
+func (dt *TC08) Interrupt() (bool, error) {
+	return (dt.sta&dtaIntEnable) != 0 &&
+		(dt.stb&(dtbFlag|dtbErrors)) != 0, nil
+}
+
+// IOT returns PC, LAC, error
+func (dt *TC08) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	link := lac & 0o10000
+	ac := mask(lac)
+	device := (ir >> 3) & 0o77
+	switch device {
+	case 0o76:
+		old := dt.sta
+		if (ir & 0o1) == 0o1 { // DTRA - Read status register A
+			ac |= dt.sta
+		}
+		if (ir & 0o2) == 0o2 { // DTCA - Clear status register A
+			dt.sta = 0
+		}
+		if (ir & 0o4) == 0o4 { // DTXA - Exclusive OR status register A
+			if (ac & dtaKeepErr) == 0 {
+				dt.stb &^= dtbErrors
+			}
+			if (ac & dtaKeepFlag) == 0 {
+				dt.stb &^= dtbFlag
+			}
+			dt.sta ^= ac &^ (dtaKeepErr | dtaKeepFlag)
+			ac = 0
+		}
+		// A function is started again even if status register A is
+		// unchanged, so that a block can be read or written again
+		if (ir & 0o6) != 0 {
+			dt.newFunction(old)
+		}
+	case 0o77:
+		if (ir & 0o1) == 0o1 { // DTSF - Skip on DECtape or error flag
+			if (dt.stb & (dtbFlag | dtbErrors)) != 0 {
+				pc = mask(pc + 1)
+			}
+		}
+		if (ir & 0o2) == 0o2 { // DTRB - Read status register B
+			ac |= dt.statusB()
+		}
+		if (ir & 0o4) == 0o4 { // DTLB - Load memory field
+			dt.stb = (dt.stb &^ dtbField) | (ac & dtbField)
+			ac = 0
+		}
+	}
+	return pc, link | ac, nil
+}
+
+// statusB returns status register B
+func (dt *TC08) statusB() uint {
+	if (dt.stb & dtbErrors) != 0 {
+		return dt.stb | dtbError
+	}
+	return dt.stb
+}
+
+// unit returns the drive selected by status register A
+func (dt *TC08) unit() *dtUnit {
+	return &dt.units[(dt.sta&dtaUnit)>>9]
+}
+
+// function returns the function in status register A
+func (dt *TC08) function() uint {
+	return (dt.sta & dtaFunction) >> 3
+}
+
+// blockCycles returns how many cycles it takes a block of d to pass
+func (dt *TC08) blockCycles(d *dtUnit) uint64 {
+	return uint64(d.blockWords+dtOverhead) * dtWordCycles
+}
+
+// stop stops all the tapes
+func (dt *TC08) stop() {
+	for i := range dt.units {
+		dt.units[i].moving = false
+	}
+	dt.cancel()
+}
+
+// cancel cancels the next block from being reached
+func (dt *TC08) cancel() {
+	dt.pending.cancel()
+	dt.pending = nil
+}
+
+// error sets the error flags in errs and stops the tapes
+func (dt *TC08) error(errs uint) {
+	dt.stb |= errs
+	dt.sta &^= dtaGo
+	dt.stop()
+}
+
+// newFunction starts the motion and function in status register A.
+// old is the previous contents of status register A.
+func (dt *TC08) newFunction(old uint) {
+	dt.wcOverflow = false
+	if (dt.sta & dtaUnit) != (old & dtaUnit) {
+		// Only the selected unit can move
+		dt.stop()
+	}
+	d := dt.unit()
+	if (dt.sta & dtaGo) == 0 {
+		d.moving = false
+		dt.cancel()
+		return
+	}
+	f := dt.function()
+	if d.image == nil || (d.writeProtect && (f == dtWrite || f == dtWriteAll)) {
+		dt.error(dtbSelect)
+		return
+	}
+
+	reverse := (dt.sta & dtaReverse) != 0
+	if !d.moving || d.reverse != reverse {
+		delay := uint64(dtAccelCycles)
+		if d.moving {
+			// Stop and then get up to speed in the other direction
+			delay *= 2
+		}
+		d.moving = true
+		d.reverse = reverse
+		d.nextAt = attachedCycles(dt.p) + delay + dt.blockCycles(d)
+	}
+	dt.cancel()
+	dt.scheduleBlock(d)
+}
+
+// scheduleBlock arranges for the next block of d to be reached
+func (dt *TC08) scheduleBlock(d *dtUnit) {
+	var e *event
+	e = dt.p.schedule(dt, d.nextAt, func() error {
+		if d.image == nil {
+			// Detached while moving
+			dt.error(dtbSelect)
+			return nil
+		}
+		block := d.pos
+		if d.reverse {
+			block--
+		}
+		if block < 0 || block >= dtBlocks {
+			dt.error(dtbEndOfTape)
+			return nil
+		}
+		if d.reverse {
+			d.pos--
+		} else {
+			d.pos++
+		}
+		err := dt.block(d, uint(block))
+		// Unless the block stopped the tape
+		if dt.pending == e {
+			d.nextAt += dt.blockCycles(d)
+			dt.scheduleBlock(d)
+		}
+		return err
+	})
+	dt.pending = e
+}
+
+// block carries out the function on block as it passes the heads
+func (dt *TC08) block(d *dtUnit, block uint) error {
+	field := (dt.stb & dtbField) >> 3
+	switch dt.function() {
+	case dtSearch:
+		wc := mask(dt.p.DataBreakRead(dtWCAddr) + 1)
+		dt.p.DataBreakWrite(dtWCAddr, wc)
+		ca := dt.p.DataBreakRead(dtWCAddr + 1)
+		dt.p.DataBreakWrite(field<<12|ca, block)
+		dt.stb |= dtbFlag
+	case dtRead, dtReadAll:
+		if dt.wcOverflow {
+			return nil
+		}
+		words := make([]uint, d.blockWords)
+		if err := readWords(d.image, int64(block*d.blockWords), words); err != nil {
+			return fmt.Errorf("TC08: %w", err)
+		}
+		if dt.function() == dtReadAll {
+			words = withHeaders(words)
+		}
+		if d.reverse {
+			reverseBlock(words)
+		}
+		i := 0
+		dt.p.threeCycleBreak(dtWCAddr, field, func(addr uint) bool {
+			dt.p.DataBreakWrite(addr, words[i])
+			i++
+			return i < len(words)
+		})
+		dt.endOfBlock()
+	case dtWrite, dtWriteAll:
+		if dt.wcOverflow {
+			return nil
+		}
+		// The rest of the block is filled with 0 if the word count
+		// overflows
+		all := dt.function() == dtWriteAll
+		n := d.blockWords
+		if all {
+			n += 2 * dtAllWords
+		}
+		words := make([]uint, n)
+		i := 0
+		dt.p.threeCycleBreak(dtWCAddr, field, func(addr uint) bool {
+			words[i] = dt.p.DataBreakRead(addr)
+			i++
+			return i < len(words)
+		})
+		if d.reverse {
+			reverseBlock(words)
+		}
+		if all {
+			words = words[dtAllWords : dtAllWords+d.blockWords]
+		}
+		if err := writeWords(d.image, int64(block*d.blockWords), words); err != nil {
+			return fmt.Errorf("TC08: %w", err)
+		}
+		dt.endOfBlock()
+	}
+	return nil
+}
+
+// endOfBlock sets the DECtape flag if the word count has overflowed
+func (dt *TC08) endOfBlock() {
+	if dt.p.DataBreakRead(dtWCAddr) == 0 {
+		dt.wcOverflow = true
+		dt.stb |= dtbFlag
+	}
+}
+
+// withHeaders returns the words of a block as transferred by Read All
+func withHeaders(words []uint) []uint {
+	// Reverse guard, lock, reverse checksum and reverse final
+	header := split18(0, 0, tdRevChecksumWord(words), 0)
+	// Final, checksum, reverse lock and guard
+	trailer := split18(0, tdChecksumWord(words), 0, 0)
+	r := append(header, words...)
+	return append(r, trailer...)
+}
+
+// split18 returns 18-bit words as 12-bit words
+func split18(ws ...uint) []uint {
+	var r []uint
+	for i := 0; i+1 < len(ws); i += 2 {
+		v := ws[i]<<18 | ws[i+1]
+		r = append(r, (v>>24)&0o7777, (v>>12)&0o7777, v&0o7777)
+	}
+	return r
+}
+
+// reverseBlock reverses the order of the words in a block and obverse
+// complements them as happens when a tape is read or written in
+// reverse
+func reverseBlock(words []uint) {
+	for i, j := 0, len(words)-1; i <= j; i, j = i+1, j-1 {
+		words[i], words[j] = obverseComplement(words[j]), obverseComplement(words[i])
+	}
+}
+
+// obverseComplement complements w and reverses the order of its
+// octal digits
+func obverseComplement(w uint) uint {
+	w = ^w
+	return (w&0o7)<<9 | (w&0o70)<<3 | (w&0o700)>>3 | (w&0o7000)>>9
+}
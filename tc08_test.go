@@ -0,0 +1,324 @@
+package pdp8
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// dtRoutine returns a routine which loads status register B with stb,
+// status register A with sta, waits for the DECtape or an error flag
+// and then halts at 0211 if there is an error, otherwise at 0212
+func dtRoutine(stb uint, sta uint) map[uint]uint {
+	return map[uint]uint{
+		0o200: 0o1220, // TAD 220
+		0o201: 0o6774, // DTLB
+		0o202: 0o1221, // TAD 221
+		0o203: 0o6766, // DTCA DTXA
+		0o204: 0o6771, // DTSF
+		0o205: 0o5204, // JMP 204
+		0o206: 0o6772, // DTRB
+		0o207: 0o7510, // SPA
+		0o210: 0o7402, // HLT
+		0o211: 0o7402, // HLT
+		0o220: stb,
+		0o221: sta,
+	}
+}
+
+// newTapeImage returns the name of a tape image file with each word
+// of block n set to n<<7 + the word's position in the block
+func newTapeImage(t *testing.T, blockWords int) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.dt")
+	f, err := openImage(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	words := make([]uint, dtBlocks*blockWords)
+	for i := range words {
+		words[i] = uint(i/blockWords)<<7 + uint(i%blockWords)
+	}
+	if err := writeWords(f, 0, words); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// newDTImage returns a controller with a tape image from newTapeImage
+// attached to unit 0
+func newDTImage(t *testing.T, blockWords int) *TC08 {
+	t.Helper()
+	dt := NewTC08()
+	if err := dt.OpenImage(0, newTapeImage(t, blockWords), false); err != nil {
+		t.Fatal(err)
+	}
+	if got := int(dt.units[0].blockWords); got != blockWords {
+		t.Fatalf("got words per block: %d, want: %d", got, blockWords)
+	}
+	return dt
+}
+
+func TestTC08_search(t *testing.T) {
+	dt := newDTImage(t, 129)
+	defer dt.Close()
+	// Search forward until block 5 is found
+	routine := map[uint]uint{
+		0o200: 0o1231, // TAD 231
+		0o201: 0o6766, // DTCA DTXA
+		0o202: 0o6771, // DTSF
+		0o203: 0o5202, // JMP 202
+		0o204: 0o1300, // TAD 300
+		0o205: 0o1232, // TAD 232
+		0o206: 0o7640, // SZA CLA
+		0o207: 0o5211, // JMP 211
+		0o210: 0o7402, // HLT
+		0o211: 0o6764, // DTXA
+		0o212: 0o5202, // JMP 202
+		0o231: 0o210,
+		0o232: 0o7773,
+	}
+	p := newDeviceMachine(t, dt, routine,
+		map[uint]uint{dtWCAddr: 0o7000, dtWCAddr + 1: 0o300})
+	if _, err := p.RunUntil(Halted(), CycleBudget(500000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o211 {
+		t.Fatalf("got PC: %04o, status B: %04o", p.pc, dt.statusB())
+	}
+	if p.mem[dtWCAddr] != 0o7006 || p.mem[dtWCAddr+1] != 0o300 {
+		t.Errorf("got word count: %04o, current address: %04o",
+			p.mem[dtWCAddr], p.mem[dtWCAddr+1])
+	}
+	want := uint64(dtAccelCycles + 6*dt.blockCycles(&dt.units[0]))
+	if p.Cycles() < want {
+		t.Errorf("got cycles: %d, want at least: %d", p.Cycles(), want)
+	}
+}
+
+func TestTC08_read(t *testing.T) {
+	cases := []struct {
+		blockWords int
+		reverse    bool
+	}{
+		{blockWords: 129},
+		{blockWords: 128},
+		{blockWords: 129, reverse: true},
+	}
+	for _, c := range cases {
+		dt := newDTImage(t, c.blockWords)
+		dt.units[0].pos = 3
+		sta := uint(0o220) // Read forward
+		block := uint(3)
+		if c.reverse {
+			sta |= dtaReverse
+			block = 2
+		}
+		// Read a block into field 1
+		p := newDeviceMachine(t, dt, dtRoutine(0o10, sta),
+			map[uint]uint{dtWCAddr: 0o7600, dtWCAddr + 1: 0o777})
+		if _, err := p.RunUntil(Halted(), CycleBudget(500000)); err != nil {
+			t.Fatal(err)
+		}
+		if p.pc != 0o212 {
+			t.Fatalf("%v - got PC: %04o, status B: %04o", c, p.pc, dt.statusB())
+		}
+		for i := uint(0); i < 0o200; i++ {
+			want := block<<7 + i
+			if c.reverse {
+				want = obverseComplement(block<<7 + uint(c.blockWords) - 1 - i)
+			}
+			if got := p.mem[0o11000+i]; got != want {
+				t.Fatalf("%v - mem[%05o] got: %04o, want: %04o",
+					c, 0o11000+i, got, want)
+			}
+		}
+		if p.mem[0o11200] != 0 {
+			t.Errorf("%v - read past word count", c)
+		}
+		if err := dt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTC08_read_again(t *testing.T) {
+	dt := newDTImage(t, 129)
+	defer dt.Close()
+	dt.units[0].pos = 3
+
+	// Read two blocks in a row with the same status register A
+	p := newDeviceMachine(t, dt, dtRoutine(0o10, 0o220), nil)
+	for block := uint(3); block <= 4; block++ {
+		p.mem[dtWCAddr] = 0o7600
+		p.mem[dtWCAddr+1] = 0o777
+		p.pc = 0o200
+		p.lac = 0
+		if _, err := p.RunUntil(Halted(), CycleBudget(500000)); err != nil {
+			t.Fatal(err)
+		}
+		if p.pc != 0o212 {
+			t.Fatalf("block: %d - got PC: %04o, status B: %04o",
+				block, p.pc, dt.statusB())
+		}
+		for i := uint(0); i < 0o200; i++ {
+			if got, want := p.mem[0o11000+i], block<<7+i; got != want {
+				t.Fatalf("block: %d - mem[%05o] got: %04o, want: %04o",
+					block, 0o11000+i, got, want)
+			}
+		}
+	}
+}
+
+func TestTC08_write(t *testing.T) {
+	dt := newDTImage(t, 129)
+	defer dt.Close()
+	dt.units[0].pos = 2
+
+	// Write 200 words, the rest of the second block is filled with 0
+	p := newDeviceMachine(t, dt, dtRoutine(0, 0o240),
+		map[uint]uint{dtWCAddr: 0o7470, dtWCAddr + 1: 0o777})
+	for i := uint(0); i < 0o310; i++ {
+		p.mem[0o1000+i] = 0o5000 + i
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(500000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o212 {
+		t.Fatalf("got PC: %04o, status B: %04o", p.pc, dt.statusB())
+	}
+	words := make([]uint, 3*129)
+	if err := readWords(dt.units[0].image, 2*129, words); err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range words {
+		want := uint(0)
+		switch {
+		case i < 0o310:
+			want = 0o5000 + uint(i)
+		case i >= 2*129:
+			want = 4<<7 + uint(i-2*129)
+		}
+		if w != want {
+			t.Fatalf("tape word %d got: %04o, want: %04o", i, w, want)
+		}
+	}
+	if dt.units[0].pos != 4 {
+		t.Errorf("got position: %d, want: 4", dt.units[0].pos)
+	}
+}
+
+func TestTC08_read_all(t *testing.T) {
+	for _, reverse := range []bool{false, true} {
+		dt := newDTImage(t, 129)
+		dt.units[0].pos = 3
+		sta := uint(0o230) // Read All forward
+		block := uint(3)
+		if reverse {
+			sta |= dtaReverse
+			block = 2
+		}
+		// Read a block and its headers into field 1
+		n := uint(129 + 2*dtAllWords)
+		p := newDeviceMachine(t, dt, dtRoutine(0o10, sta),
+			map[uint]uint{dtWCAddr: 0o10000 - n, dtWCAddr + 1: 0o777})
+		if _, err := p.RunUntil(Halted(), CycleBudget(500000)); err != nil {
+			t.Fatal(err)
+		}
+		if p.pc != 0o212 {
+			t.Fatalf("reverse: %t - got PC: %04o, status B: %04o",
+				reverse, p.pc, dt.statusB())
+		}
+
+		data := make([]uint, 129)
+		for i := range data {
+			data[i] = block<<7 + uint(i)
+		}
+		want := make([]uint, n)
+		want[4] = tdRevChecksumWord(data) << 6
+		copy(want[dtAllWords:], data)
+		want[n-5] = tdChecksum(data, false)
+		if reverse {
+			reverseBlock(want)
+		}
+		for i := uint(0); i < n; i++ {
+			if got := p.mem[0o11000+i]; got != want[i] {
+				t.Fatalf("reverse: %t - mem[%05o] got: %04o, want: %04o",
+					reverse, 0o11000+i, got, want[i])
+			}
+		}
+		if err := dt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTC08_write_all(t *testing.T) {
+	dt := newDTImage(t, 129)
+	defer dt.Close()
+	dt.units[0].pos = 2
+
+	// Write a block with its headers, only the data is stored
+	n := uint(129 + 2*dtAllWords)
+	p := newDeviceMachine(t, dt, dtRoutine(0, 0o250),
+		map[uint]uint{dtWCAddr: 0o10000 - n, dtWCAddr + 1: 0o777})
+	for i := uint(0); i < n; i++ {
+		p.mem[0o1000+i] = 0o5000 + i
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(500000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o212 {
+		t.Fatalf("got PC: %04o, status B: %04o", p.pc, dt.statusB())
+	}
+	words := make([]uint, 2*129)
+	if err := readWords(dt.units[0].image, 2*129, words); err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range words {
+		want := 0o5000 + dtAllWords + uint(i)
+		if i >= 129 {
+			want = 3<<7 + uint(i-129)
+		}
+		if w != want {
+			t.Fatalf("tape word %d got: %04o, want: %04o", i, w, want)
+		}
+	}
+}
+
+func TestTC08_errors(t *testing.T) {
+	cases := []struct {
+		name string
+		sta  uint
+		want uint
+	}{
+		{name: "no tape", sta: 0o1200, want: dtbSelect},
+		{name: "write protected", sta: 0o240, want: dtbSelect},
+		{name: "end of tape", sta: 0o600, want: dtbEndOfTape},
+	}
+	for _, c := range cases {
+		dt := newDTImage(t, 129)
+		dt.SetWriteProtect(0, true)
+		p := newDeviceMachine(t, dt, dtRoutine(0, c.sta),
+			map[uint]uint{dtWCAddr: 0o7600, dtWCAddr + 1: 0o777})
+		if _, err := p.RunUntil(Halted(), CycleBudget(500000)); err != nil {
+			t.Fatal(err)
+		}
+		if p.pc != 0o211 || (dt.stb&dtbErrors) != c.want {
+			t.Errorf("%s - got PC: %04o, status B: %04o, want errors: %04o",
+				c.name, p.pc, dt.statusB(), c.want)
+		}
+		if (dt.sta & dtaGo) != 0 {
+			t.Errorf("%s - tape still moving", c.name)
+		}
+		if err := dt.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestObverseComplement(t *testing.T) {
+	if got := obverseComplement(0o1234); got != 0o3456 {
+		t.Errorf("got: %04o, want: 3456", got)
+	}
+}
@@ -0,0 +1,527 @@
+/*
+ * A TD8E simple DECtape controller with two TU56 drives
+ *
+ * The controller is device 77.  Unlike the TC08 it leaves decoding the
+ * mark track to the program, which sees each line of the tape as it
+ * passes: one bit of the mark track and three bits of data.  A line
+ * passes every 33µs once a tape is up to speed and four lines make a
+ * 12-bit word.  Tapes are formatted with 1474 blocks of 129 words and
+ * use the same image files as the TC08.  Images with 128 word blocks
+ * have a 129th word in each block which reads as 0 and isn't stored
+ * when written.  Only the data words are written, the checksums are
+ * always worked out from the data.
+ *
+ * Each block is laid out as 6-line mark track words, whose marks read
+ * forward are:
+ *   Block mark, reverse guard, lock, reverse checksum, reverse final,
+ *   data x 86, final, checksum, reverse lock, guard, reverse block mark
+ * Read in reverse the marks are obverse complemented and so show the
+ * same codes as read forward.  The low 12 bits of the block mark word
+ * and the high 12 bits of the reverse block mark word hold the block
+ * number, so that the data register holds it when a block mark is seen
+ * in either direction.  The checksum word's high 6 bits hold the
+ * checksum and the reverse checksum word's low 6 bits hold the
+ * checksum of the block as read in reverse.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	tdUnits        = 2
+	tdBlockWords   = 129
+	tdMarkLines    = 6                             // Lines in each mark track word
+	tdHeaderLines  = 5 * tdMarkLines               // Lines in the header and in the trailer
+	tdDataLines    = tdBlockWords * 4              // Lines of data in a block
+	tdBlockLines   = 2*tdHeaderLines + tdDataLines // Lines in a block
+	tdEndZoneLines = 4096 * tdMarkLines            // Lines in each end zone
+	tdTapeLines    = 2*tdEndZoneLines + dtBlocks*tdBlockLines
+	tdLineCycles   = instructionsPerSecond * 33 / 1000000 // 33µs to pass a line
+)
+
+// Command register
+const (
+	tdcUnit    = 0o4000 // The unit selected
+	tdcReverse = 0o2000 // Move in reverse
+	tdcGo      = 0o1000 // Move the tape
+	tdcWrite   = 0o400  // Write data
+	tdcMask    = tdcUnit | tdcReverse | tdcGo | tdcWrite
+
+	tdsWriteLock = 0o200 // The unit selected is write protected
+	tdsTiming    = 0o100 // Timing or select error
+)
+
+// Mark track codes as read forward
+const (
+	tdMarkRevEnd   = 0o55
+	tdMarkFwdEnd   = 0o22
+	tdMarkBlock    = 0o26
+	tdMarkRevGuard = 0o32
+	tdMarkLock     = 0o10 // Lock, reverse checksum and reverse final
+	tdMarkData     = 0o70
+	tdMarkFinal    = 0o73 // Final, checksum and reverse lock
+	tdMarkGuard    = 0o51
+	tdMarkRevBlock = 0o45
+)
+
+var tdHeaderMarks = [5]uint{
+	tdMarkBlock, tdMarkRevGuard, tdMarkLock, tdMarkLock, tdMarkLock,
+}
+
+var tdTrailerMarks = [5]uint{
+	tdMarkFinal, tdMarkFinal, tdMarkFinal, tdMarkGuard, tdMarkRevBlock,
+}
+
+type TD8E struct {
+	p      *PDP8 // The machine attached to
+	units  [tdUnits]tdUnit
+	cmd    uint // Command register
+	window uint // The last 6 bits of the mark track
+	data   uint // Data register, shifted a line at a time
+	slf    bool // Single line flag
+	qlf    bool // Quad line flag
+	qlctr  int  // Lines since the quad line flag was last set
+	tme    bool // Timing or select error
+}
+
+// tdUnit is a TU56 drive
+type tdUnit struct {
+	image        DiskImage
+	file         *os.File // The file opened by OpenImage, if any
+	blockWords   uint
+	writeProtect bool
+	pos          int    // The next line forward, the next in reverse is pos-1
+	moving       bool   // Whether the tape is moving
+	reverse      bool   // Whether the tape is moving in reverse
+	startAt      uint64 // The cycle the tape got up to speed
+	lines        uint64 // Lines passed since startAt
+	block        int    // The block held in words
+	words        []uint // The words of block, nil if none
+}
+
+// NewTD8E returns a controller with no tapes attached
+func NewTD8E() *TD8E {
+	return &TD8E{}
+}
+
+// AttachImage attaches the tape image to drive unit.  blockWords is
+// the number of words in each block of the image, 129 or 128.
+func (td *TD8E) AttachImage(unit int, image DiskImage, blockWords int, writeProtect bool) error {
+	if unit < 0 || unit >= tdUnits {
+		return fmt.Errorf("TD8E: invalid unit: %d", unit)
+	}
+	if blockWords != 129 && blockWords != 128 {
+		return fmt.Errorf("TD8E: invalid words per block: %d", blockWords)
+	}
+	if err := td.DetachImage(unit); err != nil {
+		return err
+	}
+	td.units[unit] = tdUnit{
+		image:        image,
+		blockWords:   uint(blockWords),
+		writeProtect: writeProtect,
+	}
+	return nil
+}
+
+// OpenImage opens the tape image file filename and attaches it to
+// drive unit.  If the file is the size of a tape with 128 word blocks
+// it is used as one, otherwise it is taken to have 129 word blocks.
+// If the file doesn't exist it is created unless writeProtect is set.
+// The file is closed when detached.
+func (td *TD8E) OpenImage(unit int, filename string, writeProtect bool) error {
+	f, err := openImage(filename, writeProtect)
+	if err != nil {
+		return fmt.Errorf("TD8E: %w", err)
+	}
+	if err := td.AttachImage(unit, f, dtImageBlockWords(f), writeProtect); err != nil {
+		f.Close()
+		return err
+	}
+	td.units[unit].file = f
+	return nil
+}
+
+// DetachImage detaches the tape image from drive unit
+func (td *TD8E) DetachImage(unit int) error {
+	if unit < 0 || unit >= tdUnits {
+		return fmt.Errorf("TD8E: invalid unit: %d", unit)
+	}
+	var err error
+	if f := td.units[unit].file; f != nil {
+		err = f.Close()
+	}
+	td.units[unit] = tdUnit{}
+	return err
+}
+
+// SetWriteProtect sets the write lock switch of drive unit
+func (td *TD8E) SetWriteProtect(unit int, on bool) {
+	if unit >= 0 && unit < tdUnits {
+		td.units[unit].writeProtect = on
+	}
+}
+
+// Attach also stops the tapes
+func (td *TD8E) Attach(p *PDP8) {
+	td.p = p
+	td.stop()
+}
+
+// Closes any image files opened by OpenImage
+func (td *TD8E) Close() error {
+	var errs []error
+	for unit := range td.units {
+		if err := td.DetachImage(unit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (td *TD8E) DeviceNumbers() []int {
+	return []int{0o77}
+}
+
+func (td *TD8E) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "TD8E",
+		Description: "Simple DECtape controller",
+	}
+}
+
+// Reset clears the controller and stops the tapes
+func (td *TD8E) Reset() {
+	td.CAF()
+}
+
+// CAF clears the registers and flags and stops the tapes
+func (td *TD8E) CAF() {
+	td.cmd = 0
+	td.window = 0
+	td.data = 0
+	td.slf = false
+	td.qlf = false
+	td.qlctr = 0
+	td.tme = false
+	td.stop()
+}
+
+// Interrupt returns false as the TD8E doesn't interrupt
+func (td *TD8E) Interrupt() (bool, error) {
+	return false, nil
+}
+
+// IOT returns PC, LAC, error
+func (td *TD8E) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
+	link := lac & 0o10000
+	ac := mask(lac)
+	if err := td.update(); err != nil {
+		return pc, lac, err
+	}
+	switch ir & 0o7 {
+	case 0o1: // SDSS - Skip on single line flag
+		if td.slf {
+			pc = mask(pc + 1)
+		}
+	case 0o2: // SDST - Skip on timing error
+		if td.tme {
+			pc = mask(pc + 1)
+		}
+	case 0o3: // SDSQ - Skip on quad line flag
+		if td.qlf {
+			pc = mask(pc + 1)
+		}
+	case 0o4: // SDLC - Load command register
+		td.loadCommand(ac)
+		ac = 0
+	case 0o5: // SDLD - Load data register
+		td.data = ac
+		td.slf = false
+		td.qlf = false
+		ac = 0
+	case 0o6: // SDRC - Read command register and mark track
+		ac = td.cmd | td.window
+		if td.unit().writeProtect {
+			ac |= tdsWriteLock
+		}
+		if td.tme {
+			ac |= tdsTiming
+		}
+		td.slf = false
+		td.qlf = false
+		td.qlctr = 0
+	case 0o7: // SDRD - Read data register
+		ac = td.data
+		td.slf = false
+		td.qlf = false
+	}
+	return pc, link | ac, nil
+}
+
+var tdIOTNames = [8]string{
+	"", "SDSS", "SDST", "SDSQ", "SDLC", "SDLD", "SDRC", "SDRD",
+}
+
+// IOTName returns the mnemonic of ir as its device number is shared
+// with the TC08
+func (td *TD8E) IOTName(ir uint) (string, bool) {
+	name := tdIOTNames[ir&0o7]
+	return name, name != ""
+}
+
+// unit returns the drive selected by the command register
+func (td *TD8E) unit() *tdUnit {
+	if (td.cmd & tdcUnit) != 0 {
+		return &td.units[1]
+	}
+	return &td.units[0]
+}
+
+// stop stops all the tapes
+func (td *TD8E) stop() {
+	for i := range td.units {
+		td.units[i].moving = false
+	}
+}
+
+// loadCommand loads the command register from ac and starts or stops
+// the tape selected
+func (td *TD8E) loadCommand(ac uint) {
+	cmd := ac & tdcMask
+	if (cmd & tdcUnit) != (td.cmd & tdcUnit) {
+		// Only the selected unit can move
+		td.stop()
+	}
+	td.cmd = cmd
+	td.tme = false
+	d := td.unit()
+	if (cmd & tdcGo) == 0 {
+		d.moving = false
+		return
+	}
+	if d.image == nil || (d.writeProtect && (cmd&tdcWrite) != 0) {
+		td.tme = true
+		d.moving = false
+		return
+	}
+	reverse := (cmd & tdcReverse) != 0
+	if !d.moving || d.reverse != reverse {
+		delay := uint64(dtAccelCycles)
+		if d.moving {
+			// Stop and then get up to speed in the other direction
+			delay *= 2
+		}
+		d.moving = true
+		d.reverse = reverse
+		d.startAt = attachedCycles(td.p) + delay
+		d.lines = 0
+	}
+}
+
+// update passes the lines under the heads of the tape selected since
+// the last update
+func (td *TD8E) update() error {
+	d := td.unit()
+	now := attachedCycles(td.p)
+	if !d.moving || now < d.startAt {
+		return nil
+	}
+	n := (now - d.startAt) / tdLineCycles
+	for d.moving && d.lines < n {
+		d.lines++
+		if err := td.passLine(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// passLine reads or writes the next line of d
+func (td *TD8E) passLine(d *tdUnit) error {
+	if d.image == nil {
+		// Detached while moving
+		td.tme = true
+		d.moving = false
+		return nil
+	}
+	line := d.pos
+	if d.reverse {
+		line--
+	}
+	if line < 0 || line >= tdTapeLines {
+		// Run off the end of the tape
+		td.tme = true
+		d.moving = false
+		return nil
+	}
+	if d.reverse {
+		d.pos--
+	} else {
+		d.pos++
+	}
+
+	mark, data, err := td.line(d, line)
+	if err != nil {
+		return err
+	}
+	if d.reverse {
+		mark ^= 1
+		data ^= 0o7
+	}
+	td.window = (td.window<<1 | mark) & 0o77
+	if (td.cmd & tdcWrite) != 0 {
+		data = td.data >> 9
+		td.data = (td.data << 3) & 0o7777
+		if d.reverse {
+			data ^= 0o7
+		}
+		if err := td.writeLine(d, line, data); err != nil {
+			return err
+		}
+	} else {
+		td.data = (td.data<<3 | data) & 0o7777
+	}
+
+	td.slf = true
+	td.qlctr++
+	if td.qlctr == 4 {
+		td.qlctr = 0
+		if td.qlf {
+			td.tme = true
+		}
+		td.qlf = true
+	}
+	return nil
+}
+
+// line returns the mark track bit and data bits of line as read forward
+func (td *TD8E) line(d *tdUnit, line int) (uint, uint, error) {
+	if line < tdEndZoneLines {
+		return markBit(tdMarkRevEnd, line%tdMarkLines), 0, nil
+	}
+	line -= tdEndZoneLines
+	if line >= dtBlocks*tdBlockLines {
+		return markBit(tdMarkFwdEnd, line%tdMarkLines), 0, nil
+	}
+	block := line / tdBlockLines
+	pos := line % tdBlockLines
+	words, err := td.block(d, block)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pos >= tdHeaderLines && pos < tdHeaderLines+tdDataLines {
+		pos -= tdHeaderLines
+		w := words[pos/4]
+		return markBit(tdMarkData, pos%tdMarkLines), (w >> (9 - 3*(pos%4))) & 0o7, nil
+	}
+
+	// An 18-bit header or trailer word
+	var mark, v uint
+	n := pos / tdMarkLines
+	if n < len(tdHeaderMarks) {
+		mark = tdHeaderMarks[n]
+		switch n {
+		case 0:
+			v = uint(block)
+		case 3:
+			v = tdRevChecksumWord(words)
+		}
+	} else {
+		n -= (tdHeaderLines + tdDataLines) / tdMarkLines
+		mark = tdTrailerMarks[n]
+		switch n {
+		case 1:
+			v = tdChecksumWord(words)
+		case 4:
+			v = uint(block) << 6
+		}
+	}
+	i := pos % tdMarkLines
+	return markBit(mark, i), (v >> (15 - 3*i)) & 0o7, nil
+}
+
+// writeLine writes the data bits of line as written forward.  Only
+// lines within the data of a block are written.
+func (td *TD8E) writeLine(d *tdUnit, line int, data uint) error {
+	line -= tdEndZoneLines
+	if line < 0 || line >= dtBlocks*tdBlockLines {
+		return nil
+	}
+	block := line / tdBlockLines
+	pos := line%tdBlockLines - tdHeaderLines
+	if pos < 0 || pos >= tdDataLines {
+		return nil
+	}
+	words, err := td.block(d, block)
+	if err != nil {
+		return err
+	}
+	i := uint(pos / 4)
+	shift := 9 - 3*(pos%4)
+	words[i] = words[i]&^(0o7<<shift) | data<<shift
+	if i >= d.blockWords {
+		return nil
+	}
+	err = writeWords(d.image, int64(uint(block)*d.blockWords+i), words[i:i+1])
+	if err != nil {
+		return fmt.Errorf("TD8E: %w", err)
+	}
+	return nil
+}
+
+// block returns the words of block from the image of d
+func (td *TD8E) block(d *tdUnit, block int) ([]uint, error) {
+	if d.words != nil && d.block == block {
+		return d.words, nil
+	}
+	words := make([]uint, tdBlockWords)
+	err := readWords(d.image, int64(uint(block)*d.blockWords), words[:d.blockWords])
+	if err != nil {
+		return nil, fmt.Errorf("TD8E: %w", err)
+	}
+	d.block = block
+	d.words = words
+	return words, nil
+}
+
+// markBit returns bit i of a mark track code, counting from the first
+// line read forward
+func markBit(code uint, i int) uint {
+	return (code >> (5 - i)) & 1
+}
+
+// tdChecksumWord returns the 18-bit checksum word of a block
+func tdChecksumWord(words []uint) uint {
+	return tdChecksum(words, false) << 12
+}
+
+// tdRevChecksumWord returns the 18-bit reverse checksum word of a
+// block, which holds the checksum read in reverse as read forward
+func tdRevChecksumWord(words []uint) uint {
+	c := tdChecksum(words, true)
+	return (^c&0o7)<<3 | (^c>>3)&0o7
+}
+
+// tdChecksum returns the 6-bit checksum of the words of a block as
+// read forward or, if reverse is set, as read in reverse
+func tdChecksum(words []uint, reverse bool) uint {
+	c := uint(0o77)
+	for _, w := range words {
+		if reverse {
+			w = obverseComplement(w)
+		}
+		w ^= 0o7777
+		c ^= (w >> 6) ^ w
+	}
+	return c & 0o77
+}
@@ -0,0 +1,303 @@
+package pdp8
+
+import (
+	"testing"
+)
+
+// newTDMachine returns a machine with a TD8E which has a tape image
+// from newTapeImage attached to unit 0
+func newTDMachine(t *testing.T, blockWords int) (*PDP8, *TD8E) {
+	t.Helper()
+	td := NewTD8E()
+	if err := td.OpenImage(0, newTapeImage(t, blockWords), false); err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(WithDevice(td))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, td
+}
+
+// tdIOT executes the IOT with ac and returns whether it skipped and
+// the resulting AC
+func tdIOT(t *testing.T, td *TD8E, ir uint, ac uint) (bool, uint) {
+	t.Helper()
+	pc, lac, err := td.IOT(ir, 0o200, ac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc == 0o201, mask(lac)
+}
+
+// tdPass moves on the machine's cycles for lines to pass
+func tdPass(p *PDP8, lines int) {
+	p.cycles += uint64(lines) * tdLineCycles
+}
+
+// tdStart starts the tape with the command cmd from line pos and waits
+// for it to get up to speed
+func tdStart(t *testing.T, p *PDP8, td *TD8E, pos int, cmd uint) {
+	t.Helper()
+	td.unit().pos = pos
+	tdIOT(t, td, 0o6774, cmd) // SDLC
+	p.cycles += dtAccelCycles
+}
+
+func TestTD8E_read(t *testing.T) {
+	cases := []struct {
+		blockWords int
+		reverse    bool
+	}{
+		{blockWords: 129},
+		{blockWords: 128},
+		{blockWords: 129, reverse: true},
+	}
+	for _, c := range cases {
+		p, td := newTDMachine(t, c.blockWords)
+		words := make([]uint, tdBlockWords)
+		for i := 0; i < c.blockWords; i++ {
+			words[i] = 2<<7 + uint(i)
+		}
+		if c.reverse {
+			tdStart(t, p, td, tdEndZoneLines+3*tdBlockLines, tdcGo|tdcReverse)
+		} else {
+			tdStart(t, p, td, tdEndZoneLines+2*tdBlockLines, tdcGo)
+		}
+
+		// The block mark
+		tdPass(p, 6)
+		_, mark := tdIOT(t, td, 0o6776, 0)  // SDRC
+		_, block := tdIOT(t, td, 0o6777, 0) // SDRD
+		wantBlock := uint(2)
+		if c.reverse {
+			wantBlock = obverseComplement(2)
+		}
+		if mark&0o77 != tdMarkBlock || block != wantBlock {
+			t.Fatalf("%v - got mark: %02o, block: %04o, want block: %04o",
+				c, mark&0o77, block, wantBlock)
+		}
+
+		// The data after the rest of the header
+		for i := 0; i < (tdHeaderLines-6)/4; i++ {
+			tdPass(p, 4)
+			tdIOT(t, td, 0o6777, 0) // SDRD
+		}
+		for i := 0; i < tdBlockWords; i++ {
+			tdPass(p, 4)
+			if skip, _ := tdIOT(t, td, 0o6773, 0); !skip { // SDSQ
+				t.Fatalf("%v - word %d quad line flag not set", c, i)
+			}
+			_, got := tdIOT(t, td, 0o6777, 0) // SDRD
+			want := words[i]
+			if c.reverse {
+				want = obverseComplement(words[tdBlockWords-1-i])
+			}
+			if got != want {
+				t.Fatalf("%v - word %d got: %04o, want: %04o", c, i, got, want)
+			}
+		}
+
+		// The checksum is in the second word
+		tdPass(p, 4)
+		tdIOT(t, td, 0o6777, 0) // SDRD
+		tdPass(p, 4)
+		_, got := tdIOT(t, td, 0o6777, 0) // SDRD
+		if want := tdChecksum(words, c.reverse); got&0o77 != want {
+			t.Errorf("%v - got checksum: %02o, want: %02o", c, got&0o77, want)
+		}
+		if skip, _ := tdIOT(t, td, 0o6772, 0); skip { // SDST
+			t.Errorf("%v - timing error", c)
+		}
+		if err := td.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTD8E_write(t *testing.T) {
+	p, td := newTDMachine(t, 129)
+	defer td.Close()
+	tdStart(t, p, td, tdEndZoneLines+3*tdBlockLines, tdcGo|tdcWrite)
+	// Synchronize with the block mark and then pass the rest of the
+	// header, which isn't written
+	tdPass(p, 6)
+	tdIOT(t, td, 0o6776, 0) // SDRC
+	for i := 0; i < (tdHeaderLines-6)/4; i++ {
+		tdIOT(t, td, 0o6775, 0) // SDLD
+		tdPass(p, 4)
+	}
+	for i := uint(0); i < tdBlockWords; i++ {
+		tdIOT(t, td, 0o6775, 0o6000+i) // SDLD
+		tdPass(p, 4)
+	}
+	if skip, _ := tdIOT(t, td, 0o6772, 0); skip { // SDST
+		t.Error("timing error")
+	}
+	tdIOT(t, td, 0o6774, 0) // SDLC - Stop
+
+	words := make([]uint, 2*129)
+	if err := readWords(td.units[0].image, 3*129, words); err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range words {
+		want := 0o6000 + uint(i)
+		if i >= 129 {
+			want = 4<<7 + uint(i-129)
+		}
+		if w != want {
+			t.Fatalf("tape word %d got: %04o, want: %04o", i, w, want)
+		}
+	}
+}
+
+func TestTD8E_errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		pos   int
+		cmd   uint
+		lines int
+	}{
+		{name: "no tape", cmd: tdcUnit | tdcGo},
+		{name: "write protected", cmd: tdcGo | tdcWrite},
+		{name: "quad line flag not cleared", pos: tdEndZoneLines, cmd: tdcGo, lines: 8},
+		{name: "off the end", cmd: tdcGo | tdcReverse, lines: 1},
+	}
+	for _, c := range cases {
+		p, td := newTDMachine(t, 129)
+		td.SetWriteProtect(0, true)
+		tdStart(t, p, td, c.pos, c.cmd)
+		tdPass(p, c.lines)
+		if skip, _ := tdIOT(t, td, 0o6772, 0); !skip { // SDST
+			t.Errorf("%s - no timing error", c.name)
+		}
+		if td.unit().moving && c.lines != 8 {
+			t.Errorf("%s - tape still moving", c.name)
+		}
+		if err := td.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTD8E_search(t *testing.T) {
+	p, td := newTDMachine(t, 129)
+	defer td.Close()
+	td.units[0].pos = tdEndZoneLines + 5*tdBlockLines - 100
+
+	// Look for a block mark and then read the block number
+	routine := map[uint]uint{
+		0o200: 0o1220, // TAD 220
+		0o201: 0o6774, // SDLC
+		0o202: 0o6771, // SDSS
+		0o203: 0o5202, // JMP 202
+		0o204: 0o6776, // SDRC
+		0o205: 0o0221, // AND 221
+		0o206: 0o1222, // TAD 222
+		0o207: 0o7640, // SZA CLA
+		0o210: 0o5202, // JMP 202
+		0o211: 0o6777, // SDRD
+		0o212: 0o7402, // HLT
+		0o220: tdcGo,
+		0o221: 0o77,
+		0o222: 0o10000 - tdMarkBlock,
+	}
+	for addr, v := range routine {
+		p.mem[addr] = v
+	}
+	if _, err := p.RunUntil(Halted(), CycleBudget(100000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o213 || mask(p.lac) != 5 {
+		t.Errorf("got PC: %04o, AC: %04o", p.pc, mask(p.lac))
+	}
+	if want := uint64(dtAccelCycles + 100*tdLineCycles); p.Cycles() < want {
+		t.Errorf("got cycles: %d, want at least: %d", p.Cycles(), want)
+	}
+}
+
+// Reads a block in the way that an OS/8 handler does, by finding its
+// block mark and then reading each word when the quad line flag is set
+func TestTD8E_handler(t *testing.T) {
+	p, td := newTDMachine(t, 129)
+	defer td.Close()
+	td.units[0].pos = tdEndZoneLines + 5*tdBlockLines - 100
+
+	// Read block 6 into 1000-1200 and its checksum into 307
+	routine := map[uint]uint{
+		0o200: 0o1300, // TAD 300
+		0o201: 0o6774, // SDLC
+		0o202: 0o6771, // SDSS - Find a block mark
+		0o203: 0o5202, // JMP 202
+		0o204: 0o6776, // SDRC
+		0o205: 0o0301, // AND 301
+		0o206: 0o1302, // TAD 302
+		0o207: 0o7640, // SZA CLA
+		0o210: 0o5202, // JMP 202
+		0o211: 0o6777, // SDRD - Check the block number
+		0o212: 0o1303, // TAD 303
+		0o213: 0o7640, // SZA CLA
+		0o214: 0o5202, // JMP 202
+		0o215: 0o1304, // TAD 304
+		0o216: 0o3306, // DCA 306
+		0o217: 0o6773, // SDSQ - Skip the rest of the header
+		0o220: 0o5217, // JMP 217
+		0o221: 0o6777, // SDRD
+		0o222: 0o7200, // CLA
+		0o223: 0o2306, // ISZ 306
+		0o224: 0o5217, // JMP 217
+		0o225: 0o1305, // TAD 305
+		0o226: 0o3306, // DCA 306
+		0o227: 0o6773, // SDSQ - Read the data
+		0o230: 0o5227, // JMP 227
+		0o231: 0o6777, // SDRD
+		0o232: 0o3410, // DCA I 10
+		0o233: 0o2306, // ISZ 306
+		0o234: 0o5227, // JMP 227
+		0o235: 0o6773, // SDSQ - The final word
+		0o236: 0o5235, // JMP 235
+		0o237: 0o6777, // SDRD
+		0o240: 0o7200, // CLA
+		0o241: 0o6773, // SDSQ - The checksum word
+		0o242: 0o5241, // JMP 241
+		0o243: 0o6777, // SDRD
+		0o244: 0o0301, // AND 301
+		0o245: 0o3307, // DCA 307
+		0o246: 0o6772, // SDST
+		0o247: 0o5252, // JMP 252
+		0o250: 0o7402, // HLT - Timing error
+		0o252: 0o7200, // CLA
+		0o253: 0o6774, // SDLC - Stop the tape
+		0o254: 0o7402, // HLT
+		0o300: tdcGo,
+		0o301: 0o77,
+		0o302: 0o10000 - tdMarkBlock,
+		0o303: 0o10000 - 6,
+		0o304: 0o10000 - (tdHeaderLines-6)/4,
+		0o305: 0o10000 - tdBlockWords,
+		0o010: 0o777,
+	}
+	for addr, v := range routine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+	if _, err := p.RunUntil(Halted(), CycleBudget(200000)); err != nil {
+		t.Fatal(err)
+	}
+	if p.pc != 0o255 {
+		t.Fatalf("got PC: %04o", p.pc)
+	}
+	words := make([]uint, tdBlockWords)
+	for i := range words {
+		words[i] = 6<<7 + uint(i)
+		if got := p.mem[0o1000+i]; got != words[i] {
+			t.Fatalf("mem[%04o] got: %04o, want: %04o", 0o1000+i, got, words[i])
+		}
+	}
+	if want := tdChecksum(words, false); p.mem[0o307] != want {
+		t.Errorf("got checksum: %02o, want: %02o", p.mem[0o307], want)
+	}
+	if td.unit().moving {
+		t.Error("tape still moving")
+	}
+}
@@ -64,6 +64,57 @@ func loadBINTape(t *testing.T, p *PDP8, tty *TTY, filename string) {
 	}
 }
 
+// newDeviceMachine returns a PDP-8/E with two fields of memory and d
+// attached, with routine and then mem loaded into memory
+func newDeviceMachine(t *testing.T, d Device, routine map[uint]uint, mem map[uint]uint) *PDP8 {
+	t.Helper()
+	p, err := New(WithModel(ModelPDP8E), WithMemorySize(2*fieldSize), WithDevice(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range routine {
+		p.mem[addr] = v
+	}
+	for addr, v := range mem {
+		p.mem[addr] = v
+	}
+	return p
+}
+
+// Load paper tape in binary format using the high-speed reader
+func loadHighSpeedBINTape(t *testing.T, p *PDP8, pc *PC8E, filename string) {
+	t.Helper()
+	// Load the BIN loader
+	err := p.LoadHighSpeedRIMTape(pc, filepath.Join("fixtures", "dec-08-lbaa-pm.rim"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Run BIN loader to load supplied paper tape
+	pc.ReaderAttachTape(bufio.NewReader(f))
+	p.pc = 0o7777
+
+	// A 0 in the MSB of SR indicates the high-speed reader
+	p.sr = 0
+
+	stop, err := p.RunUntil(Halted(), CycleBudget(20000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stop.Halted {
+		t.Fatalf("Failed to execute HLT at PC: %04o", p.pc-1)
+	}
+	if mask(p.lac) != 0 || p.ir != 0o7402 {
+		t.Fatalf("Checksum fail for tape: %s", filename)
+	}
+}
+
 // TODO: For debugging - do we need this here?
 func dumpMemory(startLocation uint, mem [4096]uint) {
 	for n := startLocation; n <= 0o7777; n++ {
@@ -90,3 +141,11 @@ func (r *dummyReadWriter) Read(p []byte) (n int, err error) {
 func (r *dummyReadWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
+
+// Log the instructions in the history, oldest first
+func logHistory(t *testing.T, p *PDP8) {
+	t.Helper()
+	for _, e := range p.History() {
+		t.Log(e)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * An instruction tracer
+ *
+ * This writes a line for each instruction executed in the same
+ * format as SIMH's PDP-8 'show history' so that the two can be
+ * compared with diff.  The registers shown are those before the
+ * instruction was executed.
+ *
+ * Copyright (C) 2023 Lawrence Woodman <lwoodman@vlifesystems.com>
+ *
+ * Licensed under an MIT licence.  Please see LICENCE.md for details.
+ */
+
+package pdp8
+
+import (
+	"fmt"
+	"io"
+)
+
+// IONFilter selects instructions by whether interrupts are enabled
+type IONFilter int
+
+const (
+	IONAny IONFilter = iota // Trace whether or not interrupts are enabled
+	IONOn                   // Only trace when interrupts are enabled
+	IONOff                  // Only trace when interrupts are disabled
+)
+
+// Tracer is an Observer which writes a line for each instruction
+// executed.  It is attached with AddObserver.
+type Tracer struct {
+	NopObserver
+	// Only instructions with a PC from Start to End, inclusive, are
+	// traced.  Bits 12-14 are the field.
+	Start uint
+	End   uint
+	ION   IONFilter // Which instructions to trace by the state of ION
+
+	p         *PDP8
+	w         io.Writer
+	err       error
+	wroteHead bool
+	tracing   bool          // Whether the current instruction is being traced
+	lac, mq   uint          // The registers before the instruction
+	accessed  []MemoryWrite // The first value seen at each address accessed
+}
+
+// NewTracer returns a Tracer which writes the instructions executed
+// by p to w
+func NewTracer(p *PDP8, w io.Writer) *Tracer {
+	return &Tracer{p: p, w: w, End: 0o77777}
+}
+
+// Err returns the first error that occurred while writing the trace
+func (t *Tracer) Err() error {
+	return t.err
+}
+
+func (t *Tracer) InstructionFetched(pc uint, ir uint) {
+	t.tracing = pc >= t.Start && pc <= t.End &&
+		(t.ION == IONAny || (t.ION == IONOn) == t.p.ien)
+	t.lac = t.p.lac
+	t.mq = t.p.mq
+	t.accessed = t.accessed[:0]
+}
+
+func (t *Tracer) MemoryRead(addr uint, value uint) {
+	t.access(addr, value)
+}
+
+func (t *Tracer) MemoryWrite(addr uint, oldValue uint, newValue uint) {
+	t.access(addr, oldValue)
+}
+
+// access records the value at addr before the instruction changed it
+func (t *Tracer) access(addr uint, value uint) {
+	if !t.tracing {
+		return
+	}
+	for _, a := range t.accessed {
+		if a.Addr == addr {
+			return
+		}
+	}
+	t.accessed = append(t.accessed, MemoryWrite{Addr: addr, OldValue: value})
+}
+
+// operand returns the value at ea before the instruction was executed
+func (t *Tracer) operand(ea uint) uint {
+	for _, a := range t.accessed {
+		if a.Addr == ea {
+			return a.OldValue
+		}
+	}
+	if ea < uint(len(t.p.mem)) {
+		return t.p.mem[ea]
+	}
+	return 0
+}
+
+func (t *Tracer) InstructionExecuted(pc uint, ir uint, ea uint) {
+	if !t.tracing || t.err != nil {
+		return
+	}
+	t.tracing = false
+	if !t.wroteHead {
+		t.wroteHead = true
+		if _, t.err = fmt.Fprint(t.w, "PC     L AC    MQ    ea     IR\n\n"); t.err != nil {
+			return
+		}
+	}
+	line := fmt.Sprintf("%05o  %o %04o  %04o  ", pc, t.lac>>12, mask(t.lac), t.mq)
+	if ir < 0o6000 {
+		line += fmt.Sprintf("%05o  ", ea)
+	} else {
+		line += "       "
+	}
+	line += t.p.Disassemble(pc, ir)
+	if ir < 0o4000 {
+		line += fmt.Sprintf("  [%04o]", t.operand(ea))
+	}
+	_, t.err = fmt.Fprintln(t.w, line)
+}
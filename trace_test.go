@@ -0,0 +1,81 @@
+package pdp8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o7001, // IAC
+		0o201: 0o1410, // TAD I 10
+		0o202: 0o3211, // DCA 211
+		0o203: 0o7402, // HLT
+		0o010: 0o0211,
+		0o212: 0o0005,
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	out := &bytes.Buffer{}
+	tr := NewTracer(p, out)
+	tr.Start = 0o201
+	p.AddObserver(tr)
+
+	hlt, _, err := p.Run(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hlt {
+		t.Fatalf("Failed to execute HLT PC: %04o", p.pc-1)
+	}
+
+	want := "PC     L AC    MQ    ea     IR\n\n" +
+		"00201  0 0001  0000  00212  TAD I 10  [0005]\n" +
+		"00202  0 0006  0000  00211  DCA 211  [0000]\n" +
+		"00203  0 0000  0000         HLT\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestTracer_ION(t *testing.T) {
+	testRoutine := map[uint]uint{
+		0o200: 0o6001, // ION
+		0o201: 0o7000, // NOP
+		0o202: 0o6002, // IOF
+		0o203: 0o7402, // HLT
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, v := range testRoutine {
+		p.mem[addr] = v
+	}
+	p.pc = 0o200
+
+	out := &bytes.Buffer{}
+	tr := NewTracer(p, out)
+	tr.ION = IONOn
+	p.AddObserver(tr)
+
+	if _, _, err := p.Run(500); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "PC     L AC    MQ    ea     IR\n\n" +
+		"00201  0 0000  0000         NOP\n" +
+		"00202  0 0000  0000         IOF\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -18,9 +18,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"log/slog"
 )
 
+// ErrQuit is returned when CTRL-\ is pressed on the console keyboard
+// so that the program running the emulator can exit cleanly
+var ErrQuit = errors.New("TTY: quit")
+
 type TTY struct {
 	ttiInputBuffer      byte // A value in the input buffer
 	ttiInterruptWaiting bool // If an interrupt is waiting for TTI to be processed
@@ -43,14 +47,24 @@ type TTY struct {
 	conout  io.Writer // Console output
 	tapein  io.Reader // Paper tape reader input
 	tapeout io.Writer // Paper tape punch output
+
+	logger *slog.Logger // Where diagnostics are logged
 }
 
 func NewTTY(conin io.Reader, conout io.Writer) *TTY {
 	tty := &TTY{conin: conin, conout: conout,
-		curin: conin, curout: conout}
+		curin: conin, curout: conout, logger: discardLogger}
 	return tty
 }
 
+// SetLogger sets the logger used for diagnostics, nil stops logging
+func (t *TTY) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = discardLogger
+	}
+	t.logger = l
+}
+
 // Closes device but doesn't close any readers/writers
 // passed to it
 func (t *TTY) Close() error {
@@ -106,8 +120,8 @@ func (t *TTY) PunchStop() {
 	t.curout = t.conout
 }
 
-// Return if there is an interrupt raised
-func (t *TTY) interrupt() (bool, error) {
+// Interrupt returns if there is an interrupt raised
+func (t *TTY) Interrupt() (bool, error) {
 	err := t.poll()
 	return t.ttiInterruptWaiting || t.ttoInterruptWaiting, err
 }
@@ -128,11 +142,10 @@ func (t *TTY) read() error {
 		if t.ttiIsReaderInput {
 			t.ttiReaderPos++
 		} else {
-			// Exit on CTRL-\ from keyboard
+			// Quit on CTRL-\ from keyboard
 			if t.ttiInputBuffer == 0x1C {
-				fmt.Println("Quit")
-				os.Exit(0)
-				// TODO: use a flag to exit nicely
+				t.logger.Info("quit key pressed on console")
+				return ErrQuit
 			}
 		}
 	}
@@ -156,12 +169,35 @@ func (t *TTY) poll() error {
 	return err
 }
 
-func (t *TTY) deviceNumbers() []int {
+func (t *TTY) DeviceNumbers() []int {
 	return []int{0o3, 0o4}
 }
 
-// Returns PC, LAC, error
-func (t *TTY) iot(ir uint, pc uint, lac uint) (uint, uint, error) {
+func (t *TTY) Info() DeviceInfo {
+	return DeviceInfo{
+		Name:        "TTY",
+		Description: "Teletype keyboard/reader and printer/punch",
+	}
+}
+
+// Reset clears the flags and input buffer.  Any tapes stay attached.
+func (t *TTY) Reset() {
+	t.CAF()
+	t.ttiInputBuffer = 0
+	t.ttiIsReaderRun = false
+}
+
+// CAF clears the keyboard and printer flags
+func (t *TTY) CAF() {
+	t.ttiReadyFlag = false
+	t.ttiPendingReadyFlag = false
+	t.ttiInterruptWaiting = false
+	t.ttoReadyFlag = false
+	t.ttoInterruptWaiting = false
+}
+
+// IOT returns PC, LAC, error
+func (t *TTY) IOT(ir uint, pc uint, lac uint) (uint, uint, error) {
 	var err error
 
 	if err := t.poll(); err != nil {
@@ -232,7 +268,7 @@ func (t *TTY) iot(ir uint, pc uint, lac uint) (uint, uint, error) {
 
 			n, err := t.curout.Write([]byte{byte(lac & ttyMask)})
 			if err != nil {
-				return fmt.Errorf("TTY: %s", err)
+				return fmt.Errorf("TTY: %w", err)
 			}
 			if n != 1 {
 				return errors.New("TTY: write failed")
@@ -2,6 +2,7 @@ package pdp8
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -10,7 +11,7 @@ import (
 
 func tryIOT(t *testing.T, tty *TTY, ir uint, pc uint, lac uint, wantPC uint, wantLac uint) {
 	t.Helper()
-	gotPC, gotLac, err := tty.iot(ir, pc, lac)
+	gotPC, gotLac, err := tty.IOT(ir, pc, lac)
 	if err != nil {
 		t.Fatalf("iot: %s", err)
 	}
@@ -138,3 +139,16 @@ func TestIOT_tape_KSF_skip_if_ready(t *testing.T) {
 
 	tty.ReaderStop()
 }
+
+// CTRL-\ from the keyboard asks the program running the emulator to quit
+func TestIOT_keyboard_quit(t *testing.T) {
+	var KCC uint = 0o6032
+	rw := newDummyReadWriter()
+	tty := NewTTY(bytes.NewReader([]byte{0x1C}), rw)
+	defer tty.Close()
+
+	_, _, err := tty.IOT(KCC, 0, 0)
+	if !errors.Is(err, ErrQuit) {
+		t.Errorf("got error: %v, want: %v", err, ErrQuit)
+	}
+}